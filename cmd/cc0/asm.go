@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/compiler"
+	"flag"
+	"io/ioutil"
+)
+
+func reportAssemblyErrors(errs []*cc0_error.Error, diagnostics *cc0_error.Diagnostics) {
+	for _, err := range errs {
+		diagnostics.Report(cc0_error.Assembler, err)
+	}
+	diagnostics.PrintAllAndExitOnError()
+}
+
+// cc0 asm input [-o file]
+func asmCommand(args []string) {
+	flags := flag.NewFlagSet("asm", flag.ExitOnError)
+	destination := flags.String("o", "out", "输出到指定的文件 file，默认为 out")
+	diagnosticsFormat := flags.String("diagnostics-format", "text", "错误与警告的输出格式：text、json 或 sarif")
+	_ = flags.Parse(reorderArgs(flags, args))
+	if flags.NArg() != 1 {
+		displayUsage(true)
+	}
+	source := flags.Arg(0)
+
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		cc0_error.PrintfToStdErr("Can't open specified source file: %s\n", source)
+		cc0_error.ThrowAndExit(cc0_error.Source)
+	}
+
+	// Assembled into a buffer before the output is created, so that a failed attempt doesn't leave an empty file behind
+	var binary bytes.Buffer
+	w := bufio.NewWriter(&binary)
+	if errs := compiler.Run(&[]string{string(content)}, w); errs != nil {
+		diagnostics := cc0_error.NewDiagnostics(source, 0)
+		diagnostics.SetFormat(parseDiagnosticsFormat(*diagnosticsFormat))
+		diagnostics.SetSource(string(content))
+		reportAssemblyErrors(errs, diagnostics)
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile(*destination, binary.Bytes(), 0666); err != nil {
+		panic(err)
+	}
+}
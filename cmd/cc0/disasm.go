@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/disassembler"
+	"c0_compiler/internal/object"
+	"flag"
+	"os"
+)
+
+// cc0 disasm input [-o file]
+func disasmCommand(args []string) {
+	flags := flag.NewFlagSet("disasm", flag.ExitOnError)
+	destination := flags.String("o", "", "输出到指定的文件 file，默认为标准输出")
+	_ = flags.Parse(reorderArgs(flags, args))
+	if flags.NArg() != 1 {
+		displayUsage(true)
+	}
+	source := flags.Arg(0)
+
+	reader, err := os.Open(source)
+	if err != nil {
+		cc0_error.PrintfToStdErr("Can't open specified source file: %s\n", source)
+		cc0_error.ThrowAndExit(cc0_error.Source)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			panic(err)
+		}
+	}()
+	file, err := object.Read(reader)
+	if err != nil {
+		cc0_error.PrintfToStdErr("Can't load %s: %s\n", source, err)
+		os.Exit(1)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if *destination != "" {
+		outfile, err := os.Create(*destination)
+		if err != nil {
+			panic(err)
+		}
+		defer func() {
+			if err := outfile.Close(); err != nil {
+				panic(err)
+			}
+		}()
+		w = bufio.NewWriter(outfile)
+	}
+	for _, line := range *disassembler.Run(file) {
+		if _, err := w.WriteString(line); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
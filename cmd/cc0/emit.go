@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"c0_compiler/internal/analyzer"
+	"c0_compiler/internal/assembler"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/codegen"
+	"c0_compiler/internal/compiler"
+	"c0_compiler/internal/emit"
+	"c0_compiler/internal/optimizer"
+	"c0_compiler/internal/parser"
+	"io"
+)
+
+var emitStages = map[string]bool{"tokens": true, "ast": true, "ir": true, "cfg": true, "asm": true, "bin": true}
+
+func parseEmitOptions(stage, format string) (asJSON bool) {
+	if !emitStages[stage] {
+		cc0_error.PrintfToStdErr("Unknown stage to emit: %s\n", stage)
+		displayUsage(true)
+	}
+	if format != "text" && format != "json" {
+		cc0_error.PrintfToStdErr("Unknown emit format: %s\n", format)
+		displayUsage(true)
+	}
+	return format == "json"
+}
+
+// Runs the front end up to `stage` and writes what the stage produces. The tokens are written even with problems in
+// them, and the tree as long as it can be parsed, so that the problems can be looked into; the later stages need a
+// program without errors. The problems are printed at the end as usual.
+func emitSource(content []byte, diagnostics *cc0_error.Diagnostics, stage string, asJSON, optimizes bool,
+	w io.Writer) error {
+	defer diagnostics.PrintAllAndExitOnError()
+
+	diagnostics.SetSource(string(content))
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	p := parser.Parse(scanner, diagnostics)
+	if stage == "tokens" {
+		return emit.Tokens(w, p.Tokens(), asJSON)
+	}
+	if diagnostics.ShouldStop() {
+		return nil
+	}
+	program := analyzer.Parse(p, diagnostics)
+	if diagnostics.HasErrors() {
+		return nil
+	}
+	globalSymbolTable := analyzer.Check(p, program, diagnostics)
+	if stage == "ast" {
+		return emit.AST(w, program, asJSON)
+	}
+	if diagnostics.HasErrors() {
+		return nil
+	}
+	codegen.Generate(program, globalSymbolTable)
+	if optimizes {
+		optimizer.Run(globalSymbolTable)
+	}
+	switch stage {
+	case "ir":
+		return emit.IR(w, globalSymbolTable, asJSON)
+	case "cfg":
+		return emit.CFG(w, globalSymbolTable, asJSON)
+	}
+
+	lines := assembler.Run(globalSymbolTable, diagnostics)
+	if diagnostics.HasErrors() {
+		return nil
+	}
+	if stage == "asm" {
+		return emit.Assembly(w, *lines, asJSON)
+	}
+
+	var binary bytes.Buffer
+	binaryWriter := bufio.NewWriter(&binary)
+	if err := compiler.RunOnGeneratedAssembly(lines, binaryWriter); err != nil {
+		reportAssemblyErrors([]*cc0_error.Error{err}, diagnostics)
+		return nil
+	}
+	if err := binaryWriter.Flush(); err != nil {
+		return err
+	}
+	return emit.Binary(w, binary.Bytes(), asJSON)
+}
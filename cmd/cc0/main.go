@@ -2,25 +2,53 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"c0_compiler/internal/analyzer"
 	"c0_compiler/internal/assembler"
 	"c0_compiler/internal/cc0_error"
 	"c0_compiler/internal/compiler"
+	"c0_compiler/internal/optimizer"
 	"c0_compiler/internal/parser"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
 )
 
 const usage = `Usage:
 cc0 [options] input [-o file]
+cc0 run [-O1] [-Wname] [-ferror-limit=N] [--diagnostics-format=format] input
+cc0 disasm input [-o file]
+cc0 asm input [-o file]
 cc0 [-h]
 
+Commands:
+	run input      编译并运行 c0 源代码，或直接运行二进制目标文件
+	disasm input   将二进制目标文件反汇编为文本汇编文件，默认输出到标准输出
+	asm input      将手写的文本汇编文件翻译为二进制目标文件
+
 Options:
 	-s        将输入的 c0 源代码翻译为文本汇编文件
 	-c        将输入的 c0 源代码翻译为二进制目标文件
 	-h        显示关于编译器使用的帮助
-	-o file   输出到指定的文件 file，默认为 out`
+	-o file   输出到指定的文件 file，默认为 out
+	-O1       对生成的指令进行窥孔优化
+	-Wname    启用名为 name 的警告：unused-variable、shadow、implicit-conversion、conversion、
+	          unused-parameter、unreachable、literal-range 或 return-type，其中 unused-variable、
+	          conversion、unreachable、literal-range 和 return-type 默认启用
+	-Wno-name 关闭名为 name 的警告
+	-Werror   将所有警告视为错误
+	-ferror-limit=N
+	          最多报告 N 个错误，0 表示不限制，默认为 20
+	--diagnostics-format=text|json|sarif
+	          错误与警告的输出格式，默认为 text
+	--emit=tokens|ast|ir|cfg|asm|bin
+	          输出编译的中间结果：词法单元、语法树、各函数的指令、控制流图（Graphviz DOT）、
+	          文本汇编或二进制目标文件，
+	          指定时忽略 -s 和 -c，默认输出到标准输出
+	--emit-format=text|json
+	          --emit 的输出格式，默认为 text`
 
 func displayUsage(toStdErr bool) {
 	if toStdErr {
@@ -31,14 +59,114 @@ func displayUsage(toStdErr bool) {
 	os.Exit(0)
 }
 
+const defaultErrorLimit = 20
+
+func parseDiagnosticsFormat(name string) int {
+	format, ok := cc0_error.ParseFormat(name)
+	if !ok {
+		cc0_error.PrintfToStdErr("Unknown diagnostics format: %s\n", name)
+		displayUsage(true)
+	}
+	return format
+}
+
+// The options on how the problems in the source are reported, which the main command and `run` share so that both of
+// them report a source in the same way.
+type diagnosticsOptions struct {
+	errorLimit *int
+	format     *string
+	warnings   warningOptions
+}
+
+// Declares the options on the flags, and parses the arguments along with the `-W` options.
+func parseWithDiagnosticsOptions(flags *flag.FlagSet, args []string) *diagnosticsOptions {
+	options := &diagnosticsOptions{
+		errorLimit: flags.Int("ferror-limit", defaultErrorLimit, "最多报告 N 个错误，0 表示不限制"),
+		format:     flags.String("diagnostics-format", "text", "错误与警告的输出格式：text、json 或 sarif"),
+	}
+	options.warnings, args = extractWarningOptions(args)
+	_ = flags.Parse(reorderArgs(flags, args))
+	return options
+}
+
+func (options *diagnosticsOptions) newDiagnostics(source string) *cc0_error.Diagnostics {
+	diagnostics := cc0_error.NewDiagnostics(source, *options.errorLimit)
+	diagnostics.SetFormat(parseDiagnosticsFormat(*options.format))
+	options.warnings.applyTo(diagnostics)
+	return diagnostics
+}
+
+// Subcommands are dispatched before the flags are parsed, each of them handles the remaining arguments by itself.
+var subcommands = map[string]func(args []string){
+	"run":    runCommand,
+	"disasm": disasmCommand,
+	"asm":    asmCommand,
+}
+
+// The flag package stops at the first positional argument. Move all of them to the end so that options are also
+// recognized after the input, as in `cc0 -s input -o file`.
+func reorderArgs(flags *flag.FlagSet, args []string) []string {
+	options, positional := []string{}, []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' {
+			positional = append(positional, arg)
+			continue
+		}
+		options = append(options, arg)
+		name := strings.TrimLeft(arg, "-")
+		if strings.ContainsRune(name, '=') || i+1 >= len(args) {
+			continue
+		}
+		if f := flags.Lookup(name); f != nil {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				i++
+				options = append(options, args[i])
+			}
+		}
+	}
+	return append(options, positional...)
+}
+
+// Runs the whole front end on the source file and returns the lines of the text assembly. All the problems found
+// are printed at the end, and the process exits if any of them is an error.
+func assembleSource(content []byte, diagnostics *cc0_error.Diagnostics, optimizes bool) *[]string {
+	defer diagnostics.PrintAllAndExitOnError()
+
+	diagnostics.SetSource(string(content))
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	p := parser.Parse(scanner, diagnostics)
+	if diagnostics.ShouldStop() {
+		return nil
+	}
+	globalSymbolTable := analyzer.Run(p, diagnostics)
+	if diagnostics.HasErrors() {
+		return nil
+	}
+	if optimizes {
+		optimizer.Run(globalSymbolTable)
+	}
+	return assembler.Run(globalSymbolTable, diagnostics)
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
 	shouldShowUsage := flag.Bool("h", false, "显示关于编译器使用的帮助")
 	shouldOutputText := flag.Bool("s", false, "将输入的 c0 源代码翻译为文本汇编文件")
 	shouldOutputBinary := flag.Bool("c", false, "将输入的 c0 源代码翻译为二进制目标文件")
 	destination := flag.String("o", "out", "输出到指定的文件 file")
+	optimizes := flag.Bool("O1", false, "对生成的指令进行窥孔优化")
+	emitStage := flag.String("emit", "", "输出编译的中间结果：tokens、ast、ir、cfg、asm 或 bin")
+	emitFormat := flag.String("emit-format", "text", "--emit 的输出格式：text 或 json")
 
 	// cc0 [options] input [-o file]
-	flag.Parse()
+	options := parseWithDiagnosticsOptions(flag.CommandLine, os.Args[1:])
 	remainingArgs := flag.Args()
 	var source string
 	if len(remainingArgs) != 1 {
@@ -52,20 +180,34 @@ func main() {
 		displayUsage(false)
 	}
 
-	reader, err := os.Open(source)
+	content, err := ioutil.ReadFile(source)
 	if err != nil {
 		cc0_error.PrintfToStdErr("Can't open specified source file: %s\n", source)
 		cc0_error.ThrowAndExit(cc0_error.Source)
 	}
-	defer func() {
-		if err := reader.Close(); err != nil {
+	diagnostics := options.newDiagnostics(source)
+
+	// cc0 --emit=stage input [-o file]
+	if *emitStage != "" {
+		asJSON := parseEmitOptions(*emitStage, *emitFormat)
+		// Nothing is buffered, since the process exits right after the problems are printed
+		outfile := os.Stdout
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "o" {
+				if outfile, err = os.Create(*destination); err != nil {
+					panic(err)
+				}
+			}
+		})
+		if err := emitSource(content, diagnostics, *emitStage, asJSON, *optimizes, outfile); err != nil {
 			panic(err)
 		}
-	}()
-	scanner := bufio.NewScanner(reader)
-	p := parser.Parse(scanner)
-	globalSymbolTable := analyzer.Run(p)
-	lines := assembler.Run(globalSymbolTable)
+		if err := outfile.Close(); err != nil {
+			panic(err)
+		}
+		return
+	}
+	lines := assembleSource(content, diagnostics, *optimizes)
 
 	var outfile *os.File
 	outfile, err = os.Create(*destination)
@@ -86,7 +228,9 @@ func main() {
 			}
 		}
 	} else if *shouldOutputBinary {
-		compiler.Run(lines, w)
+		if err := compiler.RunOnGeneratedAssembly(lines, w); err != nil {
+			reportAssemblyErrors([]*cc0_error.Error{err}, diagnostics)
+		}
 	} else {
 		displayUsage(true)
 	}
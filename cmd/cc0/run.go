@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/object"
+	"c0_compiler/internal/vm"
+	"flag"
+	"io/ioutil"
+	"os"
+)
+
+// cc0 run [-O1] [-Wname] [-ferror-limit=N] [--diagnostics-format=format] input
+func runCommand(args []string) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	optimizes := flags.Bool("O1", false, "对生成的指令进行窥孔优化")
+	options := parseWithDiagnosticsOptions(flags, args)
+	if flags.NArg() != 1 {
+		displayUsage(true)
+	}
+	source := flags.Arg(0)
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		cc0_error.PrintfToStdErr("Can't open specified source file: %s\n", source)
+		cc0_error.ThrowAndExit(cc0_error.Source)
+	}
+
+	var file *object.File
+	if object.IsBinary(content) {
+		file, err = object.Read(bytes.NewReader(content))
+	} else {
+		file, err = vm.LoadAssembly(assembleSource(content, options.newDiagnostics(source), *optimizes))
+	}
+	if err != nil {
+		cc0_error.PrintfToStdErr("Can't load %s: %s\n", source, err)
+		os.Exit(1)
+	}
+
+	if err := vm.Run(file, os.Stdin, os.Stdout); err != nil {
+		cc0_error.PrintlnToStdErr(err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"c0_compiler/internal/cc0_error"
+	"strings"
+)
+
+// The `-W` options are given by the names of the categories of the warnings, which the flag package cannot declare
+// all at once, so they are taken out of the arguments before the flags are parsed.
+type warningOptions struct {
+	enabled  map[int]bool // the categories enabled by `-Wname` and disabled by `-Wno-name`
+	asErrors bool
+}
+
+func extractWarningOptions(args []string) (options warningOptions, remainingArgs []string) {
+	options.enabled = map[int]bool{}
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-W") {
+			remainingArgs = append(remainingArgs, arg)
+			continue
+		}
+		name := strings.TrimPrefix(arg, "-W")
+		if name == "error" {
+			options.asErrors = true
+			continue
+		}
+		category, ok := cc0_error.ParseWarning(strings.TrimPrefix(name, "no-"))
+		if !ok {
+			cc0_error.PrintfToStdErr("Unknown warning option: %s\n", arg)
+			displayUsage(true)
+		}
+		options.enabled[category] = !strings.HasPrefix(name, "no-")
+	}
+	return
+}
+
+func (options warningOptions) applyTo(diagnostics *cc0_error.Diagnostics) {
+	for category, enabled := range options.enabled {
+		diagnostics.SetWarningEnabled(category, enabled)
+	}
+	diagnostics.SetWarningsAsErrors(options.asErrors)
+}
@@ -1,7 +1,9 @@
 package analyzer
 
 import (
+	"c0_compiler/internal/ast"
 	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/codegen"
 	"c0_compiler/internal/instruction"
 	"c0_compiler/internal/token"
 )
@@ -9,23 +11,156 @@ import (
 type SymbolTable = instruction.SymbolTable
 type Error = cc0_error.Error
 
-var globalParser *Parser
-var globalSymbolTable, currentSymbolTable *SymbolTable
-var globalStart, currentFunction *instruction.Fn
+// All the state of a single analysis, so that different sources can be analyzed at the same time. The parsing, the
+// name resolution and the type checking share it for the tokens and the diagnostics.
+type analyzer struct {
+	globalParser *Parser
+	diagnostics  *cc0_error.Diagnostics
 
-func Run(parser *Parser) *SymbolTable {
-	globalParser = parser
-	globalStart = instruction.InitFn(token.Void)
-	globalSymbolTable = instruction.InitSymbolTable(nil, globalStart)
-	currentSymbolTable = globalSymbolTable
-	currentFunction = globalStart
+	// Where the last token read starts
+	currentLine, currentColumn int
+}
 
-	if err := analyzeVariableDeclarations(); err != nil {
-		err.DieAndReportPosition(cc0_error.Analyzer)
+// Errors are reported into `d` instead of stopping the analysis, the returned symbol table is only meaningful when
+// none has been reported.
+func Run(parser *Parser, d *cc0_error.Diagnostics) *SymbolTable {
+	program := Parse(parser, d)
+	if d.ShouldStop() {
+		return nil
 	}
-	if err := analyzeFunctionDefinitions(); err != nil {
-		err.DieAndReportPosition(cc0_error.Analyzer)
+	globalSymbolTable := Check(parser, program, d)
+	if d.HasErrors() {
+		return globalSymbolTable
 	}
+	codegen.Generate(program, globalSymbolTable)
+	return globalSymbolTable
+}
+
+// Builds the syntax tree of the tokens. The declarations and the statements with syntax errors are reported into `d`
+// and left out of the tree, so that the rest of the program can still be checked.
+func Parse(parser *Parser, d *cc0_error.Diagnostics) *ast.Program {
+	a := &analyzer{globalParser: parser, diagnostics: d}
+	return a.parseProgram()
+}
 
+// Resolves the names and checks the types of the program, which annotates the tree for the code generation. Returns
+// the global symbol table, whose functions are to be filled with their code.
+func Check(parser *Parser, program *ast.Program, d *cc0_error.Diagnostics) *SymbolTable {
+	a := &analyzer{globalParser: parser, diagnostics: d}
+	globalSymbolTable := newResolver(a).resolveProgram(program)
+	if !d.ShouldStop() {
+		newChecker(a).checkProgram(program)
+	}
 	return globalSymbolTable
 }
+
+func (a *analyzer) parseProgram() *ast.Program {
+	// <C0-program> ::= {<variable-declaration>|<struct-declaration>}{<function-definition>}
+	program := &ast.Program{Decls: a.parseVariableDeclarations(true)}
+	for a.globalParser.HasNextToken() && !a.diagnostics.ShouldStop() {
+		functions, err := a.parseFunctionDefinitions()
+		program.Decls = append(program.Decls, functions...)
+		if err != nil {
+			a.recoverFrom(err)
+		} else if next, err := a.peekNextToken(); err == nil {
+			a.recoverFrom(cc0_error.Of(cc0_error.UnexpectedTokens).On(next.Line, next.Column))
+		}
+		// A '}' can only be a stray one at the top level
+		if next, err := a.peekNextToken(); err == nil && next.Kind == token.RightBracket {
+			_, _ = a.getNextToken()
+		}
+	}
+	return program
+}
+
+// Errors without a span cover the token at where they start.
+func (a *analyzer) report(err *Error) {
+	if !err.HasPosition() {
+		err.On(a.currentLine, a.currentColumn)
+	}
+	if t := a.globalParser.TokenStartingAt(err.Line(), err.Column()); t != nil && !err.HasSpan() {
+		err.Until(t.Line, t.EndColumn)
+	}
+	a.diagnostics.Report(cc0_error.Analyzer, err)
+}
+
+// Reports an error which spans the whole node.
+func (a *analyzer) reportOn(node ast.Node, err *Error) {
+	a.report(err.On(node.Pos().Line, node.Pos().Column).Until(node.End().Line, node.End().Column))
+}
+
+// Reports a warning of the category unless the category is disabled. Warnings are reported as errors with `-Werror`.
+func (a *analyzer) warn(category int, err *Error) {
+	a.diagnostics.Warn(cc0_error.Analyzer, category, err)
+}
+
+// Reports a warning which spans the whole node.
+func (a *analyzer) warnOn(category int, node ast.Node, err *Error) {
+	a.warn(category, err.On(node.Pos().Line, node.Pos().Column).Until(node.End().Line, node.End().Column))
+}
+
+// Places the note on the name of the symbol where it is declared.
+func (a *analyzer) noteOnTheNameOf(sb *instruction.Symbol, note *Error) *Error {
+	note.On(sb.Line, sb.Column)
+	if t := a.globalParser.TokenStartingAt(sb.Line, sb.Column); t != nil {
+		note.Until(t.Line, t.EndColumn)
+	}
+	return note
+}
+
+// Panic-mode error recovery: reports the error and skips the tokens until the end of the current statement or
+// declaration, that is, right after the next ';', right after a block which is skipped as a whole, or right before
+// the '}' closing the enclosing block.
+func (a *analyzer) recoverFrom(err *Error) {
+	a.report(err)
+	depth := 0
+	for a.globalParser.HasNextToken() {
+		pos := a.getCurrentPos()
+		next, _ := a.getNextToken()
+		switch next.Kind {
+		case token.LeftBracket:
+			depth++
+		case token.RightBracket:
+			if depth == 0 {
+				a.resetHeadTo(pos)
+				return
+			}
+			depth--
+			if depth == 0 {
+				return
+			}
+		case token.Semicolon:
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+// Reads the next token, which is expected to be of `kind`, otherwise `err` is returned at where the token is, and
+// the token is left for the recovery, e.g. the '}' of a block.
+func (a *analyzer) expect(kind int, err *Error) (*Token, *Error) {
+	pos := a.getCurrentPos()
+	next, readErr := a.getNextToken()
+	if readErr != nil || next.Kind != kind {
+		err.On(a.currentLine, a.currentColumn)
+		a.resetHeadTo(pos)
+		return nil, err
+	}
+	return next, nil
+}
+
+// Reads the next token if it is of `kind`.
+func (a *analyzer) accept(kind int) (*Token, bool) {
+	pos := a.getCurrentPos()
+	next, err := a.getNextToken()
+	if err != nil || next.Kind != kind {
+		a.resetHeadTo(pos)
+		return nil, false
+	}
+	return next, true
+}
+
+func identOf(t *Token) *ast.Ident {
+	return &ast.Ident{NamePos: ast.PositionOf(t), Name: t.Value.(string)}
+}
@@ -0,0 +1,51 @@
+package analyzer
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/token"
+)
+
+// Returns the lengths of the dimensions along with where the last ']' ends.
+func (a *analyzer) parseArrayDimensions() (lengths []*ast.IntegerLiteral, rbrack ast.Position, err *Error) {
+	// <array-dimensions> ::= '['<integer-literal>']'{'['<integer-literal>']'}
+	for {
+		if _, ok := a.accept(token.LeftSquareBracket); !ok {
+			return
+		}
+		next, readErr := a.getNextToken()
+		if readErr != nil || next.Kind != token.IntegerLiteral {
+			return nil, rbrack, cc0_error.Of(cc0_error.InvalidDeclaration).On(a.currentLine, a.currentColumn).
+				WithMessage("The length of an array must be an integer literal.")
+		}
+		lengths = append(lengths, &ast.IntegerLiteral{
+			ValuePos: ast.PositionOf(next), ValueEnd: ast.EndOf(next), Value: next.Value.(int64)})
+		closing, closingErr := a.expect(token.RightSquareBracket, cc0_error.Of(cc0_error.InvalidDeclaration).
+			WithMessage("Expected a ']' after the length of the array."))
+		if closingErr != nil {
+			return nil, rbrack, closingErr
+		}
+		rbrack = ast.EndOf(closing)
+	}
+}
+
+// Reads the subscripts after the name of an array, and returns them along with where the last ']' ends.
+func (a *analyzer) parseSubscripts() (indices []ast.Expr, rbrack ast.Position, err *Error) {
+	// <subscripts> ::= '['<expression>']'{'['<expression>']'}
+	for {
+		if _, ok := a.accept(token.LeftSquareBracket); !ok {
+			return
+		}
+		index, indexErr := a.parseExpression()
+		if indexErr != nil {
+			return nil, rbrack, indexErr
+		}
+		indices = append(indices, index)
+		closing, closingErr := a.expect(token.RightSquareBracket, cc0_error.Of(cc0_error.IllegalArrayAccess).
+			WithMessage("Expected a ']' after the subscript."))
+		if closingErr != nil {
+			return nil, rbrack, closingErr
+		}
+		rbrack = ast.EndOf(closing)
+	}
+}
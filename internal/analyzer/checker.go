@@ -0,0 +1,233 @@
+package analyzer
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/token"
+)
+
+// The type checking fills the types of the expressions, makes the implicit conversions explicit by inserting
+// conversion nodes, and checks that the values and the statements are used where they are allowed. The expressions
+// with errors are given the invalid type, so that the expressions around them are not reported as well.
+type checker struct {
+	*analyzer
+	returnType ast.Type // of the function being checked
+
+	// The numbers of the loops, and of the loops and the switches, enclosing the statement being checked
+	loops, breakables int
+}
+
+func newChecker(a *analyzer) *checker {
+	return &checker{analyzer: a}
+}
+
+func (c *checker) checkProgram(program *ast.Program) {
+	for _, decl := range program.Decls {
+		if c.diagnostics.ShouldStop() {
+			return
+		}
+		switch decl := decl.(type) {
+		case *ast.VarDecl:
+			c.checkVariableDeclaration(decl)
+		case *ast.FuncDecl:
+			c.checkFunctionDefinition(decl)
+		}
+	}
+}
+
+func (c *checker) checkVariableDeclaration(decl *ast.VarDecl) {
+	// The problems of the declarators themselves have been reported by the name resolution
+	t := decl.Type.Type
+	if decl.Init == nil || len(decl.Dimensions) > 0 {
+		return
+	}
+	if t.Kind == token.Struct {
+		decl.Init = c.checkStructOperand(decl.Init, t.Struct)
+		return
+	}
+	c.checkValue(decl.Init)
+	if t.Kind != token.Void {
+		decl.Init = c.convert(decl.Init, t)
+	}
+	decl.Init = fold(decl.Init)
+}
+
+func (c *checker) checkFunctionDefinition(decl *ast.FuncDecl) {
+	if decl.Body == nil {
+		return
+	}
+	c.returnType = ast.Type{Kind: decl.Name.Symbol.Kind}
+	for _, varDecl := range decl.Body.Decls {
+		c.checkVariableDeclaration(varDecl)
+	}
+	for _, stmt := range decl.Body.Stmts {
+		c.checkStatement(stmt)
+	}
+
+	// As in C, `main` returns 0 at its end, which is not worth a warning
+	reachesTheEnd := newFlowAnalysis(c.analyzer).analyzeFunctionBody(decl.Body)
+	if reachesTheEnd && c.returnType.IsValid() && c.returnType.Kind != token.Void && decl.Name.Name != "main" {
+		rbrace := decl.Body.Rbrace
+		c.warn(cc0_error.WarnReturnType, cc0_error.Of(cc0_error.MissingReturn).On(rbrace.Line, rbrace.Column-1).
+			Until(rbrace.Line, rbrace.Column).
+			WithMessage("The function '%s' may reach its end without returning a value.", decl.Name.Name).
+			WithNote(cc0_error.Note("The function returns %s there.", zeroOf(c.returnType))))
+	}
+}
+
+func zeroOf(t ast.Type) string {
+	if t.Kind == token.Double {
+		return "0.0"
+	}
+	return "0"
+}
+
+func (c *checker) checkStatement(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		for _, varDecl := range s.Decls {
+			c.checkVariableDeclaration(varDecl)
+		}
+		for _, inner := range s.Stmts {
+			c.checkStatement(inner)
+		}
+	case *ast.ExprStmt:
+		c.checkExpression(s.X)
+		s.X = fold(s.X)
+	case *ast.IfStmt:
+		s.Cond = c.checkCondition(s.Cond)
+		c.checkStatement(s.Then)
+		if s.Else != nil {
+			c.checkStatement(s.Else)
+		}
+	case *ast.WhileStmt:
+		s.Cond = c.checkCondition(s.Cond)
+		c.checkLoopBody(s.Body)
+	case *ast.DoWhileStmt:
+		c.checkLoopBody(s.Body)
+		s.Cond = c.checkCondition(s.Cond)
+	case *ast.ForStmt:
+		for index, x := range s.Init {
+			c.checkExpression(x)
+			s.Init[index] = fold(x)
+		}
+		if s.Cond != nil {
+			s.Cond = c.checkCondition(s.Cond)
+		}
+		for index, x := range s.Update {
+			c.checkExpression(x)
+			s.Update[index] = fold(x)
+		}
+		c.checkLoopBody(s.Body)
+	case *ast.SwitchStmt:
+		c.checkSwitchStatement(s)
+	case *ast.BranchStmt:
+		c.checkBranchStatement(s)
+	case *ast.ReturnStmt:
+		c.checkReturnStatement(s)
+	case *ast.PrintStmt:
+		c.checkPrintStatement(s)
+	case *ast.ScanStmt:
+		c.checkScanStatement(s)
+	}
+}
+
+func (c *checker) checkLoopBody(body ast.Stmt) {
+	c.loops++
+	c.breakables++
+	c.checkStatement(body)
+	c.loops--
+	c.breakables--
+}
+
+func (c *checker) checkBranchStatement(s *ast.BranchStmt) {
+	if s.Kind == token.Break && c.breakables == 0 {
+		c.report(cc0_error.Of(cc0_error.JumpOutsideLoop).On(s.Keyword.Line, s.Keyword.Column).WithMessage(
+			"'break' can only be used inside a loop or a switch."))
+	}
+	if s.Kind == token.Continue && c.loops == 0 {
+		c.report(cc0_error.Of(cc0_error.JumpOutsideLoop).On(s.Keyword.Line, s.Keyword.Column).WithMessage(
+			"'continue' can only be used inside a loop."))
+	}
+}
+
+func (c *checker) checkReturnStatement(s *ast.ReturnStmt) {
+	switch {
+	case s.Result == nil:
+		if c.returnType.IsValid() && c.returnType.Kind != token.Void {
+			c.report(cc0_error.Of(cc0_error.IllegalExpression).On(s.Return.Line, s.Return.Column).WithMessage(
+				"Expected a value of %s to return.", c.returnType))
+		}
+	case c.returnType.Kind == token.Void:
+		c.checkExpression(s.Result)
+		c.reportOn(s.Result, cc0_error.Of(cc0_error.InvalidStatement).WithMessage(
+			"A void function cannot return a value."))
+	default:
+		c.checkValue(s.Result)
+		s.Result = fold(c.convert(s.Result, c.returnType))
+	}
+}
+
+func (c *checker) checkPrintStatement(s *ast.PrintStmt) {
+	for index, arg := range s.Args {
+		if _, ok := arg.(*ast.StringLiteral); ok {
+			continue
+		}
+		c.checkValue(arg)
+		s.Args[index] = fold(arg)
+	}
+}
+
+func (c *checker) checkScanStatement(s *ast.ScanStmt) {
+	t := c.checkAssignable(s.Target)
+	if t.Kind == token.Struct {
+		c.reportOn(s.Target, cc0_error.Of(cc0_error.IllegalMemberAccess).WithMessage(
+			"The struct '%s' cannot be scanned as a whole.", nameOf(s.Target)))
+	}
+	fold(s.Target)
+}
+
+func (c *checker) checkSwitchStatement(s *ast.SwitchStmt) {
+	if t := c.checkValue(s.Tag); t.IsValid() && t.Kind != token.Int && t.Kind != token.Char {
+		// The body is still worth checking
+		c.reportOn(s.Tag, cc0_error.Of(cc0_error.IllegalSwitchExpression))
+	}
+	s.Tag = fold(s.Tag)
+
+	previousLabels := map[int]*ast.CaseClause{}
+	var defaultClause *ast.CaseClause
+	c.breakables++
+	for _, clause := range s.Clauses {
+		if clause.IsDefault {
+			if defaultClause != nil {
+				c.report(cc0_error.Of(cc0_error.DuplicateCaseLabel).On(clause.Case.Line, clause.Case.Column).
+					WithMessage("The switch has more than one default label.").
+					WithNote(cc0_error.Note("Previous default label was here.").
+						On(defaultClause.Case.Line, defaultClause.Case.Column).
+						Until(defaultClause.Case.Line, defaultClause.Case.Column+len("default"))))
+			}
+			defaultClause = clause
+		} else {
+			c.checkExpression(clause.Value)
+			clause.Label = caseLabelOf(clause.Value)
+			if previous, ok := previousLabels[clause.Label]; ok {
+				c.reportOn(clause.Value, cc0_error.Of(cc0_error.DuplicateCaseLabel).WithMessage(
+					"The case label %d is duplicated.", clause.Label).
+					WithNote(cc0_error.Note("Previous case label was here.").
+						On(previous.Value.Pos().Line, previous.Value.Pos().Column).
+						Until(previous.Value.End().Line, previous.Value.End().Column)))
+			}
+			previousLabels[clause.Label] = clause
+		}
+		for _, stmt := range clause.Body {
+			c.checkStatement(stmt)
+		}
+	}
+	c.breakables--
+}
+
+// The value of a label, which is a literal that may be negated or complemented.
+func caseLabelOf(value ast.Expr) int {
+	label, _ := evaluate(value)
+	return int(label.integer)
+}
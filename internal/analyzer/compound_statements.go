@@ -1,45 +1,73 @@
 package analyzer
 
 import (
+	"c0_compiler/internal/ast"
 	"c0_compiler/internal/cc0_error"
 	"c0_compiler/internal/token"
 )
 
-func analyzeCompoundStatement() *Error {
+func (a *analyzer) parseCompoundStatement() (*ast.BlockStmt, *Error) {
 	// '{' {<variable-declaration>} <statement-seq> '}'
-	pos := getCurrentPos()
-	if next, err := getNextToken(); err != nil || next.Kind != token.LeftBracket {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+	lbrace, err := a.expect(token.LeftBracket, cc0_error.Of(cc0_error.InvalidStatement))
+	if err != nil {
+		return nil, err
 	}
-	if err := analyzeVariableDeclarations(); err != nil {
-		resetHeadTo(pos)
-		return err
+	block := &ast.BlockStmt{Lbrace: ast.PositionOf(lbrace)}
+	for _, decl := range a.parseVariableDeclarations(false) {
+		block.Decls = append(block.Decls, decl.(*ast.VarDecl))
 	}
-	if err := analyzeStatementSeq(); err != nil {
-		resetHeadTo(pos)
-		return err
+	block.Stmts = a.parseStatementSeq()
+	rbrace, err := a.expect(token.RightBracket, cc0_error.Of(cc0_error.InvalidStatement))
+	if err != nil {
+		return nil, err
 	}
-	if next, err := getNextToken(); err != nil || next.Kind != token.RightBracket {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
-	}
-	return nil
+	block.Rbrace = ast.EndOf(rbrace)
+	return block, nil
 }
 
-func analyzeStatementSeq() *Error {
+func (a *analyzer) parseStatementSeq() (stmts []ast.Stmt) {
 	// {<statement>}
-	for {
-		if err := analyzeStatement(); err != nil {
-			return nil
+	for !a.diagnostics.ShouldStop() {
+		// The sequence ends right before the '}' closing the block
+		if next, err := a.peekNextToken(); err != nil || next.Kind == token.RightBracket {
+			return
+		}
+		start := a.getCurrentPos()
+		if stmt, err := a.parseStatement(); err != nil {
+			a.recoverFrom(err)
+			stmts = append(stmts, a.badStatementFrom(start))
+		} else {
+			stmts = append(stmts, stmt)
 		}
 	}
+	return
 }
 
-func analyzeStatement() *Error {
+// Stands for the statement starting at `start` which has been skipped by the recovery, with the identifiers read in
+// it.
+func (a *analyzer) badStatementFrom(start int) *ast.BadStmt {
+	end := a.getCurrentPos()
+	a.resetHeadTo(start)
+	from := ast.Position{Line: a.currentLine, Column: a.currentColumn}
+	bad := &ast.BadStmt{From: from, To: from}
+	for a.getCurrentPos() < end {
+		next, err := a.getNextToken()
+		if err != nil {
+			break
+		}
+		if next.Kind == token.Identifier {
+			bad.Names = append(bad.Names, identOf(next))
+		}
+		bad.To = ast.EndOf(next)
+	}
+	return bad
+}
+
+func (a *analyzer) parseStatement() (ast.Stmt, *Error) {
 	// <statement> ::=
-	//		'{' <statement-seq> '}'
+	//		'{' {<variable-declaration>} <statement-seq> '}'
 	// 		|<condition-statement>
+	// 		|<switch-statement>
 	// 		|<loop-statement>
 	// 		|<jump-statement>
 	// 		|<print-statement>
@@ -48,69 +76,43 @@ func analyzeStatement() *Error {
 	// 		|<function-call>';'
 	// 		|';'
 
-	// '{' <statement-seq> '}'
-	pos := getCurrentPos()
-	next, err := getNextToken()
-	if err == nil && next.Kind == token.LeftBracket {
-		if err := analyzeStatementSeq(); err != nil {
-			resetHeadTo(pos)
-			return err
-		}
-		if next, err := getNextToken(); err != nil || next.Kind != token.RightBracket {
-			resetHeadTo(pos)
-			return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
-		}
-		return nil
-	}
-	resetHeadTo(pos)
-
-	// <condition-statement>
-	if err := analyzeConditionStatement(); err == nil {
-		return nil
+	next, err := a.peekNextToken()
+	if err != nil {
+		return nil, cc0_error.Of(cc0_error.NoMoreTokens).On(a.currentLine, a.currentColumn)
 	}
-	resetHeadTo(pos)
 
-	// <loop-statement>
-	if err := analyzeLoopStatement(); err == nil {
-		return nil
-	}
-	resetHeadTo(pos)
-
-	// <jump-statement>
-	if err := analyzeJumpStatement(); err == nil {
-		return nil
-	}
-	resetHeadTo(pos)
-
-	// <print-statement> | <scan-statement>
-	if err := analyzeIOStatement(); err == nil {
-		return nil
-	}
-	resetHeadTo(pos)
-
-	// <assignment-expression>';'
-	if err := analyzeAssignmentExpression(); err == nil {
-		if next, err := getNextToken(); err != nil || next.Kind != token.Semicolon {
-			return cc0_error.Of(cc0_error.InvalidStatement)
+	switch next.Kind {
+	case token.LeftBracket:
+		return a.parseCompoundStatement()
+	case token.If:
+		return a.parseConditionStatement()
+	case token.Switch:
+		return a.parseSwitchStatement()
+	case token.Case, token.Default:
+		return nil, cc0_error.Of(cc0_error.IllegalCaseLabel).On(next.Line, next.Column).WithMessage(
+			"'%s' can only be used inside a switch.", next.Value)
+	case token.While, token.Do, token.For:
+		return a.parseLoopStatement()
+	case token.Break, token.Continue, token.Return:
+		return a.parseJumpStatement()
+	case token.Print, token.Scan:
+		return a.parseIOStatement()
+	case token.Identifier:
+		// <assignment-expression>';' | <function-call>';'
+		x, err := a.parseAssignmentExpression(true)
+		if err != nil {
+			return nil, err
 		}
-		return nil
-	}
-	resetHeadTo(pos)
-
-	// <function-call>';'
-	if err := analyzeFunctionCall(); err == nil {
-		if next, err := getNextToken(); err != nil || next.Kind != token.Semicolon {
-			return cc0_error.Of(cc0_error.InvalidStatement)
+		semicolon, err := a.expect(token.Semicolon, cc0_error.Of(cc0_error.InvalidStatement).WithMessage(
+			"Expected a ';' at the end of the statement."))
+		if err != nil {
+			return nil, err
 		}
-		return nil
+		return &ast.ExprStmt{X: x, Semicolon: ast.EndOf(semicolon)}, nil
+	case token.Semicolon:
+		// ';'
+		_, _ = a.getNextToken()
+		return &ast.EmptyStmt{Semicolon: ast.PositionOf(next)}, nil
 	}
-	resetHeadTo(pos)
-
-	// ';'
-	if next, err := getNextToken(); err != nil || next.Kind != token.Semicolon {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
-	}
-
-	return nil
+	return nil, cc0_error.Of(cc0_error.InvalidStatement).On(next.Line, next.Column)
 }
@@ -0,0 +1,116 @@
+package analyzer
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/token"
+)
+
+func (a *analyzer) parseCondition() (ast.Expr, *Error) {
+	// <condition> ::= <logical-or-condition>
+	return a.parseLogicalOrCondition()
+}
+
+func (a *analyzer) parseLogicalOrCondition() (ast.Expr, *Error) {
+	// <logical-or-condition> ::= <logical-and-condition>{'||'<logical-and-condition>}
+	x, err := a.parseLogicalAndCondition()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator, ok := a.accept(token.LogicalOr)
+		if !ok {
+			return x, nil
+		}
+		y, err := a.parseLogicalAndCondition()
+		if err != nil {
+			return nil, err
+		}
+		x = &ast.BinaryExpr{X: x, OpPos: ast.PositionOf(operator), Op: token.LogicalOr, Y: y}
+	}
+}
+
+func (a *analyzer) parseLogicalAndCondition() (ast.Expr, *Error) {
+	// <logical-and-condition> ::= <logical-not-condition>{'&&'<logical-not-condition>}
+	x, err := a.parseLogicalNotCondition()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator, ok := a.accept(token.LogicalAnd)
+		if !ok {
+			return x, nil
+		}
+		y, err := a.parseLogicalNotCondition()
+		if err != nil {
+			return nil, err
+		}
+		x = &ast.BinaryExpr{X: x, OpPos: ast.PositionOf(operator), Op: token.LogicalAnd, Y: y}
+	}
+}
+
+func (a *analyzer) parseLogicalNotCondition() (ast.Expr, *Error) {
+	// <logical-not-condition> ::= '!'<logical-not-condition> | '('<condition>')' | <relation>
+	pos := a.getCurrentPos()
+	next, err := a.getNextToken()
+	if err != nil {
+		return nil, cc0_error.Of(cc0_error.IncompleteExpression).On(a.currentLine, a.currentColumn)
+	}
+	if next.Kind == token.LogicalNot {
+		x, err := a.parseLogicalNotCondition()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.UnaryExpr{OpPos: ast.PositionOf(next), Op: token.LogicalNot, X: x}, nil
+	}
+	a.resetHeadTo(pos)
+
+	// A '(' may start either a parenthesized condition or a parenthesized expression, e.g. `(a + b) > c`. The
+	// relation is tried first since it's the only one that can be followed by a relational operator.
+	relation, relationErr := a.parseRelation()
+	if relationErr == nil || next.Kind != token.LeftParenthesis {
+		return relation, relationErr
+	}
+	a.resetHeadTo(pos)
+	_, _ = a.getNextToken()
+	x, conditionErr := a.parseLogicalOrCondition()
+	if conditionErr != nil {
+		// Tells about the attempt which goes further
+		if isBefore(conditionErr, relationErr) {
+			return nil, relationErr
+		}
+		return nil, conditionErr
+	}
+	rparen, rparenErr := a.expect(token.RightParenthesis, cc0_error.Of(cc0_error.IncompleteExpression).WithMessage(
+		"Expected a ')' to close the condition."))
+	if rparenErr != nil {
+		return nil, rparenErr
+	}
+	return &ast.ParenExpr{Lparen: ast.PositionOf(next), X: x, Rparen: ast.EndOf(rparen)}, nil
+}
+
+func (a *analyzer) parseRelation() (ast.Expr, *Error) {
+	// <relation> ::= <expression>[<relational-operator><expression>]
+	x, err := a.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	pos := a.getCurrentPos()
+	next, readErr := a.getNextToken()
+	if readErr != nil || !next.IsARelationalOperator() {
+		a.resetHeadTo(pos)
+		return x, nil
+	}
+	y, err := a.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.BinaryExpr{X: x, OpPos: ast.PositionOf(next), Op: next.Kind, Y: y}, nil
+}
+
+func isBefore(lhs, rhs *Error) bool {
+	if lhs.Line() != rhs.Line() {
+		return lhs.Line() < rhs.Line()
+	}
+	return lhs.Column() < rhs.Column()
+}
@@ -0,0 +1,297 @@
+package analyzer
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/token"
+	"math"
+)
+
+// A value known without running the program. The chars and the ints are kept in `integer`, and the doubles in `real`.
+// The values are computed as the VM does at run time: the ints wrap around on overflow, and the chars are not
+// truncated by the arithmetic but only by the conversions.
+type constant struct {
+	kind    int
+	integer int32
+	real    float64
+}
+
+func constantOf(sb *instruction.Symbol) constant {
+	if real, ok := sb.Value.(float64); ok {
+		return constant{kind: token.Double, real: real}
+	}
+	return constant{kind: sb.Kind, integer: sb.Value.(int32)}
+}
+
+// The value kept by the symbol of a constant.
+func (c constant) value() interface{} {
+	if c.kind == token.Double {
+		return c.real
+	}
+	return c.integer
+}
+
+// Converts by the same rules as the conversion instructions. A double is truncated into an int, which saturates when
+// it is out of the range and is 0 for a NaN, and a char keeps the lowest byte of an int.
+func (c constant) convertTo(kind int) constant {
+	switch {
+	case c.kind == kind:
+	case kind == token.Double:
+		c.real = float64(c.integer)
+	case c.kind == token.Double:
+		c.integer = truncate(c.real)
+		if kind == token.Char {
+			c.integer = int32(uint8(c.integer))
+		}
+	case kind == token.Char:
+		c.integer = int32(uint8(c.integer))
+	}
+	c.kind = kind
+	return c
+}
+
+func truncate(real float64) int32 {
+	switch {
+	case math.IsNaN(real):
+		return 0
+	case real >= math.MaxInt32:
+		return math.MaxInt32
+	case real <= math.MinInt32:
+		return math.MinInt32
+	}
+	return int32(real)
+}
+
+// Compares the values as `icmp` and `dcmp` do, which is -1, 0 or 1. A NaN is equal to everything.
+func compare(lhs, rhs constant) int32 {
+	if lhs.kind == token.Double {
+		switch {
+		case lhs.real > rhs.real:
+			return 1
+		case lhs.real < rhs.real:
+			return -1
+		}
+		return 0
+	}
+	switch {
+	case lhs.integer > rhs.integer:
+		return 1
+	case lhs.integer < rhs.integer:
+		return -1
+	}
+	return 0
+}
+
+// A value stands in a condition when it is not 0.
+func (c constant) stands() bool {
+	return compare(c, constant{kind: c.kind}) != 0
+}
+
+func booleanOf(b bool) constant {
+	if b {
+		return constant{kind: token.Int, integer: 1}
+	}
+	return constant{kind: token.Int}
+}
+
+// The value of an expression built from the literals and the constants whose values are known, or false if it has
+// anything else or is a division or a modulo by 0, which is left to fail at run time. The shifts count by the lowest 5
+// bits of the right operand, as the generated code does. The kinds are worked out from the operands as the type
+// checking does, so that the value of a constant can be found before the checking.
+func evaluate(x ast.Expr) (constant, bool) {
+	switch e := x.(type) {
+	case *ast.IntegerLiteral:
+		return constant{kind: token.Int, integer: int32(e.Value)}, true
+	case *ast.CharLiteral:
+		return constant{kind: token.Char, integer: e.Value}, true
+	case *ast.DoubleLiteral:
+		return constant{kind: token.Double, real: e.Value}, true
+	case *ast.Ident:
+		if e.Symbol == nil || !e.Symbol.IsACompileTimeConstant() {
+			return constant{}, false
+		}
+		return constantOf(e.Symbol), true
+	case *ast.ParenExpr:
+		return evaluate(e.X)
+	case *ast.UnaryExpr:
+		return evaluateUnaryExpression(e)
+	case *ast.BinaryExpr:
+		return evaluateBinaryExpression(e)
+	case *ast.CastExpr:
+		if value, ok := evaluate(e.X); ok && (ast.Type{Kind: e.To.Kind}).IsArithmetic() {
+			return value.convertTo(e.To.Kind), true
+		}
+	case *ast.ConversionExpr:
+		if value, ok := evaluate(e.X); ok {
+			return value.convertTo(e.Type().Kind), true
+		}
+	}
+	return constant{}, false
+}
+
+func evaluateUnaryExpression(e *ast.UnaryExpr) (constant, bool) {
+	value, ok := evaluate(e.X)
+	if !ok {
+		return value, false
+	}
+	switch {
+	case e.Op == token.LogicalNot:
+		return booleanOf(!value.stands()), true
+	case e.Op == token.BitwiseNot && value.kind == token.Double:
+		// Rejected by the type checking
+		return value, false
+	case e.Op == token.BitwiseNot:
+		value.integer = ^value.integer
+	case e.Op == token.MinusSign && value.kind == token.Double:
+		value.real = -value.real
+	case e.Op == token.MinusSign:
+		value.integer = -value.integer
+	}
+	return value, true
+}
+
+func evaluateBinaryExpression(e *ast.BinaryExpr) (constant, bool) {
+	lhs, ok := evaluate(e.X)
+	if !ok {
+		return lhs, false
+	}
+	rhs, ok := evaluate(e.Y)
+	if !ok {
+		return rhs, false
+	}
+	switch {
+	case e.Op == token.LogicalAnd:
+		return booleanOf(lhs.stands() && rhs.stands()), true
+	case e.Op == token.LogicalOr:
+		return booleanOf(lhs.stands() || rhs.stands()), true
+	}
+
+	kind := lhs.kind
+	if rhs.kind > kind {
+		kind = rhs.kind
+	}
+	lhs, rhs = lhs.convertTo(kind), rhs.convertTo(kind)
+	if isARelationalOperator(e.Op) {
+		return booleanOf(holds(e.Op, compare(lhs, rhs))), true
+	}
+	_, isAnIntegerOperator := integerOperators[e.Op]
+	switch {
+	case kind == token.Double && isAnIntegerOperator:
+		// Rejected by the type checking
+		return constant{}, false
+	case kind == token.Double:
+		return constant{kind: kind, real: applyToReals(e.Op, lhs.real, rhs.real)}, true
+	case (e.Op == token.DivisionSign || e.Op == token.ModuloSign) && rhs.integer == 0:
+		return constant{}, false
+	}
+	return constant{kind: kind, integer: applyToIntegers(e.Op, lhs.integer, rhs.integer)}, true
+}
+
+func holds(relation int, comparison int32) bool {
+	switch relation {
+	case token.LessThan:
+		return comparison < 0
+	case token.LessThanOrEqual:
+		return comparison <= 0
+	case token.EqualTo:
+		return comparison == 0
+	case token.GreaterThanOrEqual:
+		return comparison >= 0
+	case token.GreaterThan:
+		return comparison > 0
+	}
+	return comparison != 0
+}
+
+func applyToReals(operator int, lhs, rhs float64) float64 {
+	switch operator {
+	case token.PlusSign:
+		return lhs + rhs
+	case token.MinusSign:
+		return lhs - rhs
+	case token.MultiplicationSign:
+		return lhs * rhs
+	}
+	return lhs / rhs
+}
+
+func applyToIntegers(operator int, lhs, rhs int32) int32 {
+	switch operator {
+	case token.PlusSign:
+		return lhs + rhs
+	case token.MinusSign:
+		return lhs - rhs
+	case token.MultiplicationSign:
+		return lhs * rhs
+	case token.ModuloSign:
+		return lhs % rhs
+	case token.BitwiseAnd:
+		return lhs & rhs
+	case token.BitwiseOr:
+		return lhs | rhs
+	case token.BitwiseXor:
+		return lhs ^ rhs
+	case token.LeftShift:
+		return lhs << uint32(rhs&31)
+	case token.RightShift:
+		return lhs >> uint32(rhs&31)
+	}
+	return lhs / rhs
+}
+
+// Replaces the parts of the checked expression whose values are known by literals, so that each of them is pushed by
+// a single instruction. The expressions with errors are left as they are.
+func fold(x ast.Expr) ast.Expr {
+	if !x.Type().IsValid() {
+		return x
+	}
+	switch x.(type) {
+	case *ast.IntegerLiteral, *ast.CharLiteral, *ast.DoubleLiteral:
+		return x
+	}
+	if value, ok := evaluate(x); ok {
+		return literalInPlaceOf(x, value)
+	}
+
+	switch e := x.(type) {
+	case *ast.ParenExpr:
+		e.X = fold(e.X)
+	case *ast.UnaryExpr:
+		e.X = fold(e.X)
+	case *ast.BinaryExpr:
+		e.X, e.Y = fold(e.X), fold(e.Y)
+	case *ast.CastExpr:
+		e.X = fold(e.X)
+	case *ast.ConversionExpr:
+		e.X = fold(e.X)
+	case *ast.AssignExpr:
+		if index, ok := e.Lhs.(*ast.IndexExpr); ok {
+			fold(index)
+		}
+		e.Rhs = fold(e.Rhs)
+	case *ast.CallExpr:
+		for index, arg := range e.Args {
+			e.Args[index] = fold(arg)
+		}
+	case *ast.IndexExpr:
+		for index, subscript := range e.Indices {
+			e.Indices[index] = fold(subscript)
+		}
+	}
+	return x
+}
+
+// A literal of the value, which takes the place of `x` in the source. The type of `x` is kept, so a char which is
+// not a valid character is given as an integer literal of the char type.
+func literalInPlaceOf(x ast.Expr, value constant) ast.Expr {
+	pos, end := x.Pos(), x.End()
+	t := x.Type()
+	value = value.convertTo(t.Kind)
+	switch {
+	case t.Kind == token.Double:
+		return &ast.DoubleLiteral{Typed: ast.Typed{T: t}, ValuePos: pos, ValueEnd: end, Value: value.real}
+	case t.Kind == token.Char && value.integer >= 0 && value.integer <= math.MaxInt8:
+		return &ast.CharLiteral{Typed: ast.Typed{T: t}, ValuePos: pos, ValueEnd: end, Value: value.integer}
+	}
+	return &ast.IntegerLiteral{Typed: ast.Typed{T: t}, ValuePos: pos, ValueEnd: end, Value: int64(value.integer)}
+}
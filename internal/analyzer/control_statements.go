@@ -1,95 +1,81 @@
 package analyzer
 
 import (
+	"c0_compiler/internal/ast"
 	"c0_compiler/internal/cc0_error"
-	"c0_compiler/internal/instruction"
 	"c0_compiler/internal/token"
 )
 
-func analyzeConditionStatement() *Error {
+func (a *analyzer) parseConditionStatement() (ast.Stmt, *Error) {
 	// <condition-statement> ::=  'if' '(' <condition> ')' <statement> ['else' <statement>]
 
-	pos := getCurrentPos()
-	if next, err := getNextToken(); err != nil || next.Kind != token.If {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+	ifToken, err := a.expect(token.If, cc0_error.Of(cc0_error.InvalidStatement))
+	if err != nil {
+		return nil, err
 	}
-	if next, err := getNextToken(); err != nil || next.Kind != token.LeftParenthesis {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+	if _, err := a.expect(token.LeftParenthesis, cc0_error.Of(cc0_error.InvalidStatement)); err != nil {
+		return nil, err
 	}
-	if err := analyzeCondition(); err != nil {
-		resetHeadTo(pos)
-		return err
+	cond, err := a.parseCondition()
+	if err != nil {
+		return nil, err
 	}
-	if next, err := getNextToken(); err != nil || next.Kind != token.RightParenthesis {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+	if _, err := a.expect(token.RightParenthesis, cc0_error.Of(cc0_error.InvalidStatement)); err != nil {
+		return nil, err
 	}
-	conditionalJumpLine := currentFunction.GetCurrentLine()
-
-	if err := analyzeStatement(); err != nil {
-		return err
+	then, err := a.parseStatement()
+	if err != nil {
+		return nil, err
 	}
+	stmt := &ast.IfStmt{If: ast.PositionOf(ifToken), Cond: cond, Then: then}
 
-	currentFunction.Append(instruction.Nop)
-	offsetOfFirstLineAfterIf := currentFunction.GetCurrentOffset() - 1
-
-	ifOffset := currentFunction.GetCurrentOffset()
-	conditionalJumpLine.SetFirstOperandTo(ifOffset)
-
-	pos = getCurrentPos()
-	if next, err := getNextToken(); err != nil || next.Kind != token.Else {
-		resetHeadTo(pos)
-		return nil
+	if _, ok := a.accept(token.Else); !ok {
+		return stmt, nil
 	}
-
-	if err := analyzeStatement(); err != nil {
-		resetHeadTo(pos)
-		return err
+	if stmt.Else, err = a.parseStatement(); err != nil {
+		return nil, err
 	}
-
-	currentOffset := currentFunction.GetCurrentOffset()
-	currentFunction.ChangeInstructionTo(offsetOfFirstLineAfterIf, instruction.Jmp, currentOffset)
-
-	return nil
+	return stmt, nil
 }
 
-func analyzeJumpStatement() *Error {
-	// <jump-statement> ::= <return-statement>
-	return analyzeReturnStatement()
+func (a *analyzer) parseJumpStatement() (ast.Stmt, *Error) {
+	// <jump-statement> ::= 'break' ';' | 'continue' ';' | <return-statement>
+	next, err := a.peekNextToken()
+	if err != nil {
+		return nil, cc0_error.Of(cc0_error.InvalidStatement).On(a.currentLine, a.currentColumn)
+	}
+	if next.Kind == token.Return {
+		return a.parseReturnStatement()
+	}
+	if next.Kind != token.Break && next.Kind != token.Continue {
+		return nil, cc0_error.Of(cc0_error.InvalidStatement).On(next.Line, next.Column)
+	}
+	_, _ = a.getNextToken()
+	semicolon, semicolonErr := a.expect(token.Semicolon, cc0_error.Of(cc0_error.InvalidStatement).WithMessage(
+		"Expected a ';' at the end of the statement."))
+	if semicolonErr != nil {
+		return nil, semicolonErr
+	}
+	return &ast.BranchStmt{Keyword: ast.PositionOf(next), Kind: next.Kind, Semicolon: ast.EndOf(semicolon)}, nil
 }
 
-func analyzeReturnStatement() *Error {
+func (a *analyzer) parseReturnStatement() (ast.Stmt, *Error) {
 	// <return-statement> ::= 'return' [<expression>] ';'
-	pos := getCurrentPos()
-	if next, err := getNextToken(); err != nil || next.Kind != token.Return {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+	returnToken, err := a.expect(token.Return, cc0_error.Of(cc0_error.InvalidStatement))
+	if err != nil {
+		return nil, err
 	}
-
-	if currentFunction.ReturnType != token.Void {
-		kind, err := analyzeExpression()
-		if err != nil {
-			return err
-		}
-		if kind != currentFunction.ReturnType {
-			convertType(kind, currentFunction.ReturnType)
+	stmt := &ast.ReturnStmt{Return: ast.PositionOf(returnToken)}
+	if next, err := a.peekNextToken(); err == nil && next.Kind != token.Semicolon {
+		var resultErr *Error
+		if stmt.Result, resultErr = a.parseExpression(); resultErr != nil {
+			return nil, resultErr
 		}
 	}
-
-	if next, err := getNextToken(); err != nil || next.Kind != token.Semicolon {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
-	}
-
-	switch currentFunction.ReturnType {
-	case token.Double:
-		currentFunction.Append(instruction.Dret)
-	case token.Int, token.Char:
-		currentFunction.Append(instruction.Iret)
-	case token.Void:
-		currentFunction.Append(instruction.Ret)
+	semicolon, err := a.expect(token.Semicolon, cc0_error.Of(cc0_error.InvalidStatement))
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	stmt.Semicolon = ast.EndOf(semicolon)
+	return stmt, nil
 }
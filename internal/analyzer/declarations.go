@@ -1,171 +1,166 @@
 package analyzer
 
 import (
+	"c0_compiler/internal/ast"
 	"c0_compiler/internal/cc0_error"
-	"c0_compiler/internal/instruction"
 	"c0_compiler/internal/token"
 )
 
-var currentInitializationType int
-
-func analyzeDeclaratorList(isConstant bool) *Error {
-	// <init-declarator-list> ::= <init-declarator>{','<init-declarator>}
-	if err := analyzeInitDeclarator(isConstant); err != nil {
-		return err
-	}
-	for {
-		pos := getCurrentPos()
-		next, err := getNextToken()
-		if !(err == nil && next.Kind == token.Comma) {
-			resetHeadTo(pos)
-			return nil
-		}
-		if err := analyzeInitDeclarator(isConstant); err != nil {
-			return err
-		}
-	}
-}
-
-func analyzeVariableDeclarations() *Error {
-	for {
-		pos := getCurrentPos()
-		next, err := getNextToken()
-		if err != nil || (!next.IsATypeSpecifier() && next.Kind != token.Const) {
-			resetHeadTo(pos)
-			return nil
+// Reads the declarations until something else, and recovers from their errors. The struct declarations are only kept
+// at the global scope.
+func (a *analyzer) parseVariableDeclarations(isGlobal bool) (decls []ast.Decl) {
+	for !a.diagnostics.ShouldStop() {
+		pos := a.getCurrentPos()
+		next, err := a.getNextToken()
+		if err != nil || (!next.IsATypeSpecifier() && next.Kind != token.Const && next.Kind != token.Struct) {
+			a.resetHeadTo(pos)
+			return
 		}
 		if next.Kind == token.Const {
-			if next, err := getNextToken(); err != nil || !next.IsATypeSpecifier() {
-				resetHeadTo(pos)
-				return nil
+			if next, err = a.getNextToken(); err != nil || (!next.IsATypeSpecifier() && next.Kind != token.Struct) {
+				a.resetHeadTo(pos)
+				return
 			}
 		}
-		if next, err := getNextToken(); err != nil || next.Kind != token.Identifier {
-			resetHeadTo(pos)
-			return nil
+		if next.Kind == token.Struct {
+			if next, err := a.getNextToken(); err != nil || next.Kind != token.Identifier {
+				a.resetHeadTo(pos)
+				return
+			}
+			if next, err := a.peekNextToken(); err == nil && next.Kind == token.LeftBracket {
+				a.resetHeadTo(pos)
+				decl, err := a.parseStructDeclaration()
+				switch {
+				case err != nil:
+					a.recoverFrom(err)
+				case !isGlobal:
+					a.report(cc0_error.Of(cc0_error.InvalidDeclaration).On(decl.Struct.Line, decl.Struct.Column).
+						WithMessage("A struct can only be declared at the global scope."))
+				default:
+					decls = append(decls, decl)
+				}
+				continue
+			}
+		}
+		if next, err := a.getNextToken(); err != nil || next.Kind != token.Identifier {
+			a.resetHeadTo(pos)
+			return
 		}
-		if next, err := getNextToken(); err != nil || (next.Kind != token.AssignmentSign &&
-			next.Kind != token.Comma && next.Kind != token.Semicolon) {
-			resetHeadTo(pos)
-			return nil
+		if next, err := a.getNextToken(); err != nil || (next.Kind != token.AssignmentSign &&
+			next.Kind != token.Comma && next.Kind != token.Semicolon && next.Kind != token.LeftSquareBracket) {
+			a.resetHeadTo(pos)
+			return
 		}
-		resetHeadTo(pos)
-		if err := analyzeVariableDeclaration(); err != nil {
-			return err
+		a.resetHeadTo(pos)
+		declarators, declErr := a.parseVariableDeclaration()
+		for _, declarator := range declarators {
+			decls = append(decls, declarator)
+		}
+		if declErr != nil {
+			// Skips the whole declaration from its start
+			a.resetHeadTo(pos)
+			a.recoverFrom(declErr)
 		}
 	}
+	return
 }
 
-func analyzeVariableDeclaration() *Error {
+// Returns the declarators parsed so far along with the error, so that the variables declared before the error are
+// still known.
+func (a *analyzer) parseVariableDeclaration() ([]*ast.VarDecl, *Error) {
 	// <variable-declaration> ::= [<const-qualifier>]<type-specifier><init-declarator-list>';'
 
 	// [<const-qualifier>]
-	pos := getCurrentPos()
-	next, err := getNextToken()
-	if err != nil {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.IncompleteVariableDeclaration).On(currentLine, currentColumn)
-	}
-	isAConstant := false
-	if next.Kind == token.Const {
-		isAConstant = true
-		next, err = getNextToken()
-		if err != nil {
-			return cc0_error.Of(cc0_error.IncompleteVariableDeclaration).On(currentLine, currentColumn)
-		}
-	}
+	_, isConstant := a.accept(token.Const)
 
 	// <type-specifier>
-	if !next.IsATypeSpecifier() {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.IncompleteVariableDeclaration).On(currentLine, currentColumn)
+	typeSpec, err := a.parseTypeSpecifier()
+	if err != nil {
+		return nil, err
 	}
-	currentInitializationType = next.Kind
 
-	// <init-declarator-list>
-	if err := analyzeDeclaratorList(isAConstant); err != nil {
-		resetHeadTo(pos)
-		return err
+	// <init-declarator-list> ::= <init-declarator>{','<init-declarator>}
+	var decls []*ast.VarDecl
+	for {
+		decl, err := a.parseInitDeclarator(isConstant, typeSpec)
+		if decl != nil {
+			decls = append(decls, decl)
+		}
+		if err != nil {
+			return decls, err
+		}
+		if _, ok := a.accept(token.Comma); !ok {
+			break
+		}
 	}
 
 	// ;
-	if next, err = getNextToken(); err != nil || next.Kind != token.Semicolon {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.IncompleteVariableDeclaration).On(currentLine, currentColumn)
+	if _, err := a.expect(token.Semicolon, cc0_error.Of(cc0_error.IncompleteVariableDeclaration)); err != nil {
+		return decls, err
 	}
-	return nil
+	return decls, nil
 }
 
-func analyzeInitDeclarator(isConstant bool) *Error {
-	// <init-declarator> ::= <identifier>[<initializer>]
+// The declarator is returned along with an error in its initializer, which is replaced by a bad expression.
+func (a *analyzer) parseInitDeclarator(isConstant bool, typeSpec *ast.TypeSpec) (*ast.VarDecl, *Error) {
+	// <init-declarator> ::= <identifier>[<array-dimensions>]['='<expression>]
 
 	// <identifier>
-	pos := getCurrentPos()
-	next, err := getNextToken()
+	next, err := a.getNextToken()
 	if err != nil {
-		return cc0_error.Of(cc0_error.IncompleteVariableDeclaration).On(currentLine, currentColumn)
+		return nil, cc0_error.Of(cc0_error.IncompleteVariableDeclaration).On(a.currentLine, a.currentColumn)
 	}
 	if next.Kind != token.Identifier {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidDeclaration).On(currentLine, currentColumn)
+		return nil, cc0_error.Of(cc0_error.InvalidDeclaration).On(a.currentLine, a.currentColumn)
 	}
-	identifier := next.Value.(string)
+	decl := &ast.VarDecl{IsConstant: isConstant, Type: typeSpec, Name: identOf(next)}
 
-	if isConstant {
-		if err := currentSymbolTable.AddAConstant(identifier, currentInitializationType); err != nil {
-			return err
-		}
-	} else {
-		if err := currentSymbolTable.AddAVariable(identifier, currentInitializationType); err != nil {
-			return err
+	// [<array-dimensions>]
+	if next, err := a.peekNextToken(); err == nil && next.Kind == token.LeftSquareBracket {
+		var dimensionsErr *Error
+		if decl.Dimensions, decl.Rbrack, dimensionsErr = a.parseArrayDimensions(); dimensionsErr != nil {
+			return nil, dimensionsErr
 		}
 	}
 
-	// <initializer> ::= '='<expression>
-	pos = getCurrentPos()
-	next, err = getNextToken()
-	if err != nil {
-		if isConstant {
-			return cc0_error.Of(cc0_error.IncompleteExpression).On(currentLine, currentColumn)
+	// ['='<expression>]
+	if _, ok := a.accept(token.AssignmentSign); ok {
+		start, _ := a.peekNextToken()
+		init, initErr := a.parseExpression()
+		if initErr != nil {
+			decl.Init = a.badExpressionFrom(start, initErr)
+			return decl, initErr
 		}
-		return nil
-	}
-	if next.Kind != token.AssignmentSign {
-		resetHeadTo(pos)
-		if isConstant {
-			return cc0_error.Of(cc0_error.IncompleteExpression).On(currentLine, currentColumn)
-		}
-		if currentInitializationType == token.Double {
-			currentFunction.Append(instruction.Snew, 2)
-		} else {
-			currentFunction.Append(instruction.Snew, 1)
-		}
-		return nil
-	}
-
-	address := currentSymbolTable.GetAddressOf(identifier)
-	if currentKind := currentSymbolTable.GetSymbolNamed(identifier).Kind; currentKind == token.Double {
-		currentFunction.Append(instruction.Snew, 2)
-	} else {
-		currentFunction.Append(instruction.Ipush, 0)
-	}
-	currentFunction.Append(instruction.Loada, currentSymbolTable.GetLevelDiff(identifier), address)
-	kind, anotherErr := analyzeExpression()
-	if anotherErr != nil {
-		return anotherErr
+		decl.Init = init
 	}
+	return decl, nil
+}
 
-	if kind != currentInitializationType {
-		convertType(kind, currentInitializationType)
+// Stands for the expression starting at `start` which has the error.
+func (a *analyzer) badExpressionFrom(start *Token, err *Error) *ast.BadExpr {
+	bad := &ast.BadExpr{To: ast.Position{Line: err.Line(), Column: err.Column()}}
+	bad.From = bad.To
+	if start != nil {
+		bad.From = ast.PositionOf(start)
 	}
+	return bad
+}
 
-	switch currentInitializationType {
-	case token.Void:
-		cc0_error.ThrowAndExit(cc0_error.Parser)
-	case token.Double:
-		currentFunction.Append(instruction.Dstore)
-	case token.Int, token.Char:
-		currentFunction.Append(instruction.Istore)
+// The name of a struct is only read here, and it is the name resolution that finds the struct.
+func (a *analyzer) parseTypeSpecifier() (*ast.TypeSpec, *Error) {
+	// <type-specifier> ::= 'void' | 'char' | 'int' | 'double' | 'struct' <identifier>
+	next, err := a.getNextToken()
+	if err != nil || (!next.IsATypeSpecifier() && next.Kind != token.Struct) {
+		return nil, cc0_error.Of(cc0_error.InvalidDeclaration).On(a.currentLine, a.currentColumn)
+	}
+	typeSpec := &ast.TypeSpec{Keyword: ast.PositionOf(next), Kind: next.Kind}
+	if next.Kind == token.Struct {
+		name, err := a.expect(token.Identifier, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+			"Expected the name of a struct after 'struct'."))
+		if err != nil {
+			return nil, err
+		}
+		typeSpec.StructName = identOf(name)
 	}
-	return nil
+	return typeSpec, nil
 }
@@ -1,496 +1,274 @@
 package analyzer
 
 import (
+	"c0_compiler/internal/ast"
 	"c0_compiler/internal/cc0_error"
-	"c0_compiler/internal/instruction"
 	"c0_compiler/internal/token"
 )
 
-func getConvertInstruction(source, dest int) []int {
-	if source == token.Void || dest == token.Void {
-		cc0_error.ThrowAndExit(cc0_error.Analyzer)
-	}
-	if (source == token.Int || source == token.Char) && dest == token.Double {
-		return []int{instruction.I2d}
-	} else if source == token.Double && dest == token.Int {
-		return []int{instruction.D2i}
-	} else if source == token.Double && dest == token.Char {
-		return []int{instruction.D2i, instruction.I2c}
-	} else if source == token.Int && dest == token.Char {
-		return []int{instruction.I2c}
-	}
-	return []int{0}
+func (a *analyzer) parseExpression() (ast.Expr, *Error) {
+	// <expression> ::= <inclusive-or-expression>
+	return a.parseInclusiveOrExpression()
 }
 
-func convertType(source, dest int) {
-	if source == dest {
-		return
-	}
-	for _, inst := range getConvertInstruction(source, dest) {
-		currentFunction.Append(inst)
-	}
-}
+// The binary operators are of the precedence levels of C, from the loosest to the tightest: '|', '^', '&', the shifts,
+// the additive operators and the multiplicative operators, each of which is left associative.
 
-func convergeToLargerType(lhs, rhs int) int {
-	if lhs > rhs {
-		return lhs
-	}
-	return rhs
+func (a *analyzer) parseInclusiveOrExpression() (ast.Expr, *Error) {
+	// <inclusive-or-expression> ::= <exclusive-or-expression>{'|'<exclusive-or-expression>}
+	return a.parseBinaryExpression(a.parseExclusiveOrExpression, isA(token.BitwiseOr))
 }
 
-func computeType(lhs, rhs, previousOffset int) int {
-	if lhs != rhs {
-		convergedKind := convergeToLargerType(lhs, rhs)
-		if lhs != convergedKind {
-			currentFunction.ReplaceNopAt(previousOffset, getConvertInstruction(lhs, convergedKind)[0])
-		} else {
-			convertType(rhs, convergedKind)
-		}
-		return convergedKind
-	}
-	return lhs
+func (a *analyzer) parseExclusiveOrExpression() (ast.Expr, *Error) {
+	// <exclusive-or-expression> ::= <and-expression>{'^'<and-expression>}
+	return a.parseBinaryExpression(a.parseAndExpression, isA(token.BitwiseXor))
 }
 
-func analyzeCondition() *Error {
-	// <condition> ::= <expression>[<relational-operator><expression>]
-
-	pos := getCurrentPos()
-	kind, err := analyzeExpression()
-	if err != nil {
-		resetHeadTo(pos)
-		return err
-	}
-	currentFunction.Append(instruction.Nop)
-	previousOffset := currentFunction.GetCurrentOffset() - 1
-	pos = getCurrentPos()
-	next, anotherErr := getNextToken()
-	if anotherErr != nil || !next.IsARelationalOperator() {
-		resetHeadTo(pos)
-		currentFunction.ChangeInstructionTo(previousOffset, instruction.Je, 0)
-		return nil
-	}
-	operator := next.Kind
-	anotherKind, err := analyzeExpression()
-	if err != nil {
-		resetHeadTo(pos)
-		return err
-	}
-
-	kind = computeType(kind, anotherKind, previousOffset)
-	if kind == token.Int || kind == token.Char {
-		currentFunction.Append(instruction.Icmp)
-	} else {
-		currentFunction.Append(instruction.Dcmp)
-	}
-
-	// Only jump when the condition doesn't stand
-	switch operator {
-	case token.LessThan:
-		currentFunction.Append(instruction.Jge, 0)
-	case token.LessThanOrEqual:
-		currentFunction.Append(instruction.Jg, 0)
-	case token.EqualTo:
-		currentFunction.Append(instruction.Jne, 0)
-	case token.GreaterThanOrEqual:
-		currentFunction.Append(instruction.Jl, 0)
-	case token.GreaterThan:
-		currentFunction.Append(instruction.Jle, 0)
-	case token.NotEqualTo:
-		currentFunction.Append(instruction.Je, 0)
-	}
-	return nil
+func (a *analyzer) parseAndExpression() (ast.Expr, *Error) {
+	// <and-expression> ::= <shift-expression>{'&'<shift-expression>}
+	return a.parseBinaryExpression(a.parseShiftExpression, isA(token.BitwiseAnd))
 }
 
-func analyzeExpression() (int, *Error) {
-	// <expression> ::= <additive-expression>
-	return analyzeAdditiveExpression()
+func (a *analyzer) parseShiftExpression() (ast.Expr, *Error) {
+	// <shift-expression> ::= <additive-expression>{<shift-operator><additive-expression>}
+	return a.parseBinaryExpression(a.parseAdditiveExpression, (*Token).IsAShiftOperator)
 }
 
-func analyzeAdditiveExpression() (int, *Error) {
+func (a *analyzer) parseAdditiveExpression() (ast.Expr, *Error) {
 	// <additive-expression> ::= <multiplicative-expression>{<additive-operator><multiplicative-expression>}
-
-	// <multiplicative-expression>
-	kind, err := analyzeMultiplicativeExpression()
-	if err != nil {
-		return 0, err
-	}
-
-	currentFunction.Append(instruction.Nop)
-	previousOffset := currentFunction.GetCurrentOffset() - 1
-
-	// {<additive-operator><multiplicative-expression>}
-	for {
-		pos := getCurrentPos()
-		next, err := getNextToken()
-		if err != nil || !next.IsAnAdditiveOperator() {
-			resetHeadTo(pos)
-			return kind, nil
-		}
-		operator := next.Kind
-		anotherKind, anotherErr := analyzeMultiplicativeExpression()
-		if anotherErr != nil {
-			resetHeadTo(pos)
-			return 0, anotherErr
-		}
-
-		kind = computeType(kind, anotherKind, previousOffset)
-		if operator == token.PlusSign {
-			if kind == token.Double {
-				currentFunction.Append(instruction.Dadd)
-			} else {
-				currentFunction.Append(instruction.Iadd)
-			}
-		} else {
-			if kind == token.Double {
-				currentFunction.Append(instruction.Dsub)
-			} else {
-				currentFunction.Append(instruction.Isub)
-			}
-		}
-	}
+	return a.parseBinaryExpression(a.parseMultiplicativeExpression, (*Token).IsAnAdditiveOperator)
 }
 
-func analyzeCastExpressionHelper(pos int, kindStack []int) (int, *Error) {
-	unaryKind, anotherErr := analyzeUnaryExpression()
-	if anotherErr != nil {
-		resetHeadTo(pos)
-		return 0, anotherErr
-	}
-	kind := 0
-	if len(kindStack) == 0 {
-		kind = unaryKind
-	} else {
-		lastKind := unaryKind
-		for len(kindStack) > 0 {
-			lastPos := len(kindStack) - 1
-			kind = kindStack[lastPos]
-			if kind != lastKind {
-				convertType(lastKind, kind)
-			}
-			lastKind = kind
-			kindStack = kindStack[0:lastPos]
-		}
-	}
-	return kind, nil
+func (a *analyzer) parseMultiplicativeExpression() (ast.Expr, *Error) {
+	// <multiplicative-expression> ::= <cast-expression>{<multiplicative-operator><cast-expression>}
+	return a.parseBinaryExpression(a.parseCastExpression, (*Token).IsAMultiplicativeOperator)
 }
 
-func analyzeCastExpression() (int, *Error) {
-	// <cast-expression> ::= {'('<type-specifier>')'}<unary-expression>
-	pos := getCurrentPos()
-	kind := 0
-	next, err := getNextToken()
-	typeStack := []int{}
+// Reads the operands of a precedence level with `parseOperand` and joins them from the left by the operators of the
+// level.
+func (a *analyzer) parseBinaryExpression(parseOperand func() (ast.Expr, *Error),
+	isAnOperator func(*Token) bool) (ast.Expr, *Error) {
+	// <operand>
+	x, err := parseOperand()
 	if err != nil {
-		resetHeadTo(pos)
-		return 0, cc0_error.Of(cc0_error.IncompleteExpression)
+		return nil, err
 	}
-	if next.Kind == token.LeftParenthesis {
-		next, err := getNextToken()
-		if err != nil || !next.IsATypeSpecifier() {
-			resetHeadTo(pos)
-			return analyzeCastExpressionHelper(pos, typeStack)
-		}
-		kind = next.Kind
-		typeStack = append(typeStack, kind)
 
-		next, err = getNextToken()
-		if err != nil || next.Kind != token.RightParenthesis {
-			return 0, cc0_error.Of(cc0_error.IncompleteExpression)
+	// {<operator><operand>}
+	for {
+		pos := a.getCurrentPos()
+		next, readErr := a.getNextToken()
+		if readErr != nil || !isAnOperator(next) {
+			a.resetHeadTo(pos)
+			return x, nil
 		}
-		for {
-			anotherPos := getCurrentPos()
-			next, err = getNextToken()
-			if err != nil {
-				resetHeadTo(anotherPos)
-				return 0, cc0_error.Of(cc0_error.IncompleteExpression)
-			}
-			if next.Kind != token.LeftParenthesis {
-				resetHeadTo(anotherPos)
-				break
-			}
-			next, err = getNextToken()
-			if err != nil || !next.IsATypeSpecifier() {
-				resetHeadTo(anotherPos)
-				break
-			}
-			nextKind := next.Kind
-			typeStack = append(typeStack, nextKind)
-
-			next, err = getNextToken()
-			if err != nil || next.Kind != token.RightParenthesis {
-				resetHeadTo(anotherPos)
-				return 0, cc0_error.Of(cc0_error.IncompleteExpression)
-			}
+		y, err := parseOperand()
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		resetHeadTo(pos)
+		x = &ast.BinaryExpr{X: x, OpPos: ast.PositionOf(next), Op: next.Kind, Y: y}
 	}
-	return analyzeCastExpressionHelper(pos, typeStack)
 }
 
-func analyzeMultiplicativeExpression() (int, *Error) {
-	// <multiplicative-expression> ::= <cast-expression>{<multiplicative-operator><cast-expression>}
-
-	// <cast-expression>
-	kind, err := analyzeCastExpression()
-	if err != nil && kind == 0 {
-		return 0, err
+func isA(kind int) func(*Token) bool {
+	return func(t *Token) bool {
+		return t.Kind == kind
 	}
+}
 
-	// {<multiplicative-operator><unary-expression>}
-	for {
-		pos := getCurrentPos()
-		next, err := getNextToken()
-		if err != nil || !next.IsAMultiplicativeOperator() {
-			resetHeadTo(pos)
-			return kind, nil
-		}
-		currentFunction.Append(instruction.Nop)
-		previousOffset := currentFunction.GetCurrentOffset() - 1
-		anotherKind, anotherErr := analyzeUnaryExpression()
-		if err != nil {
-			resetHeadTo(pos)
-			return 0, anotherErr
-		}
-		kind = computeType(kind, anotherKind, previousOffset)
+func (a *analyzer) parseCastExpression() (ast.Expr, *Error) {
+	// <cast-expression> ::= {'('<type-specifier>')'}<unary-expression>
 
-		if next.Kind == token.MultiplicationSign {
-			if kind == token.Double {
-				currentFunction.Append(instruction.Dmul)
-			} else {
-				currentFunction.Append(instruction.Imul)
+	// A '(' not followed by a type starts a parenthesized expression
+	pos := a.getCurrentPos()
+	if lparen, ok := a.accept(token.LeftParenthesis); ok {
+		if next, err := a.getNextToken(); err == nil && next.IsATypeSpecifier() {
+			to := &ast.TypeSpec{Keyword: ast.PositionOf(next), Kind: next.Kind}
+			if _, err := a.expect(token.RightParenthesis, cc0_error.Of(cc0_error.IncompleteExpression)); err != nil {
+				return nil, err
 			}
-		} else {
-			if kind == token.Double {
-				currentFunction.Append(instruction.Ddiv)
-			} else {
-				currentFunction.Append(instruction.Idiv)
+			x, err := a.parseCastExpression()
+			if err != nil {
+				return nil, err
 			}
+			return &ast.CastExpr{Lparen: ast.PositionOf(lparen), To: to, X: x}, nil
 		}
+		a.resetHeadTo(pos)
 	}
+	return a.parseUnaryExpression()
 }
 
-func analyzeUnaryExpression() (int, *Error) {
+func (a *analyzer) parseUnaryExpression() (ast.Expr, *Error) {
 	// <unary-expression> ::= [<unary-operator>]<primary-expression>
 
 	// [<unary-operator>]
-	shouldBeNegated := false
-	pos := getCurrentPos()
-	next, err := getNextToken()
+	next, err := a.peekNextToken()
 	if err != nil {
-		return 0, cc0_error.Of(cc0_error.IncompleteExpression).On(currentLine, currentColumn)
+		return nil, cc0_error.Of(cc0_error.IncompleteExpression).On(a.currentLine, a.currentColumn)
 	}
-	if next.IsAnUnaryOperator() {
-		if next.Kind == token.MinusSign {
-			shouldBeNegated = true
-		}
-	} else {
-		resetHeadTo(pos)
+	if !next.IsAnUnaryOperator() {
+		return a.parsePrimaryExpression()
 	}
+	_, _ = a.getNextToken()
 
 	// <primary-expression>
-	kind, anotherErr := analyzePrimaryExpression()
-	if anotherErr != nil {
-		resetHeadTo(pos)
-		return 0, anotherErr
-	}
-
-	if shouldBeNegated {
-		if kind == token.Double {
-			currentFunction.Append(instruction.Dneg)
-		} else {
-			currentFunction.Append(instruction.Ineg)
-		}
+	x, primaryErr := a.parsePrimaryExpression()
+	if primaryErr != nil {
+		return nil, primaryErr
 	}
-	return kind, nil
+	return &ast.UnaryExpr{OpPos: ast.PositionOf(next), Op: next.Kind, X: x}, nil
 }
 
-func analyzePrimaryExpression() (int, *Error) {
+func (a *analyzer) parsePrimaryExpression() (ast.Expr, *Error) {
 	// <primary-expression> ::=
 	//     '('<expression>')'
-	//    | <identifier>
-	//    | <integer-literal>
+	//    | <identifier>[<subscripts>]
+	//    | <member-access>
 	//    | <function-call>
+	//    | <integer-literal>
+	//    | <double-literal>
+	//    | <char-literal>
 
-	pos := getCurrentPos()
-	next, err := getNextToken()
-	kind := 0
+	next, err := a.getNextToken()
 	if err != nil {
-		return 0, cc0_error.Of(cc0_error.IncompleteExpression).On(currentLine, currentColumn)
+		return nil, cc0_error.Of(cc0_error.IncompleteExpression).On(a.currentLine, a.currentColumn)
 	}
-
-	// '('<expression>')'
-	if next.Kind == token.LeftParenthesis {
-		var anotherErr *Error
-		kind, anotherErr = analyzeExpression()
-		if anotherErr != nil {
-			return 0, anotherErr
-		}
-		next, err = getNextToken()
+	switch next.Kind {
+	case token.LeftParenthesis:
+		// '('<expression>')'
+		x, err := a.parseExpression()
 		if err != nil {
-			return 0, cc0_error.Of(cc0_error.IncompleteExpression).On(currentLine, currentColumn)
-		}
-		if next.Kind != token.RightParenthesis {
-			return 0, cc0_error.Of(cc0_error.IllegalExpression).On(currentLine, currentColumn)
+			return nil, err
 		}
-	} else if next.Kind == token.Identifier { // <identifier> || <function-call>
-		identifier := next.Value.(string)
-		sb := currentSymbolTable.GetSymbolNamed(identifier)
-		if sb == nil {
-			return 0, cc0_error.Of(cc0_error.UndefinedIdentifier).On(currentLine, currentColumn)
+		rparen, readErr := a.getNextToken()
+		if readErr != nil {
+			return nil, cc0_error.Of(cc0_error.IncompleteExpression).On(a.currentLine, a.currentColumn)
 		}
-		kind = sb.Kind
-		if sb.IsCallable {
-			resetHeadTo(pos) // `analyzeFunctionCall` needs the identifier, thus the reset
-			if err := analyzeFunctionCall(); err != nil {
-				return 0, err
-			}
-		} else {
-			currentFunction.Append(instruction.Loada, currentSymbolTable.GetLevelDiff(identifier), sb.Address)
-			if sb.Kind == token.Double {
-				currentFunction.Append(instruction.Dload)
-			} else {
-				currentFunction.Append(instruction.Iload)
-			}
+		if rparen.Kind != token.RightParenthesis {
+			return nil, cc0_error.Of(cc0_error.IllegalExpression).On(a.currentLine, a.currentColumn)
 		}
-	} else if next.Kind == token.IntegerLiteral {
-		// <integer-literal>
-		currentFunction.Append(instruction.Ipush, int(next.Value.(int64)))
-		kind = token.Int
-	} else if next.Kind == token.DoubleLiteral {
-		address := globalSymbolTable.AddALiteral(instruction.ConstantKindDouble, next.Value)
-		currentFunction.Append(instruction.Loadc, -address)
-		kind = token.Double
-	} else if next.Kind == token.CharLiteral {
-		currentFunction.Append(instruction.Bipush, int(next.Value.(int32)))
-		kind = token.Char
-	} else {
-		return 0, cc0_error.Of(cc0_error.IllegalExpression).On(currentLine, currentColumn)
+		return &ast.ParenExpr{Lparen: ast.PositionOf(next), X: x, Rparen: ast.EndOf(rparen)}, nil
+	case token.Identifier:
+		return a.parseOperand(next)
+	case token.IntegerLiteral, token.DoubleLiteral, token.CharLiteral:
+		return literalOf(next), nil
 	}
-	return kind, nil
+	return nil, cc0_error.Of(cc0_error.IllegalExpression).On(a.currentLine, a.currentColumn)
 }
 
-func analyzeAssignmentExpression() *Error {
-	// <assignment-expression> ::= <identifier><assignment-operator><expression>
-	pos := getCurrentPos()
-	next, err := getNextToken()
-	if err != nil || next.Kind != token.Identifier {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.IncompleteExpression).On(currentLine, currentColumn)
-	}
-
-	// pre read
-	preReadPos := getCurrentPos()
-	theOneAfterNext, err := getNextToken()
-	resetHeadTo(preReadPos)
-	if err == nil && theOneAfterNext.Kind != token.AssignmentSign {
-		return cc0_error.Of(cc0_error.IncompleteExpression)
+func literalOf(t *Token) ast.Expr {
+	switch t.Kind {
+	case token.IntegerLiteral:
+		return &ast.IntegerLiteral{ValuePos: ast.PositionOf(t), ValueEnd: ast.EndOf(t), Value: t.Value.(int64)}
+	case token.DoubleLiteral:
+		return &ast.DoubleLiteral{ValuePos: ast.PositionOf(t), ValueEnd: ast.EndOf(t), Value: t.Value.(float64)}
+	case token.CharLiteral:
+		return &ast.CharLiteral{ValuePos: ast.PositionOf(t), ValueEnd: ast.EndOf(t), Value: t.Value.(int32)}
 	}
+	return &ast.StringLiteral{ValuePos: ast.PositionOf(t), ValueEnd: ast.EndOf(t), Value: t.Value.(string)}
+}
 
-	identifier := next.Value.(string)
-	if currentSymbolTable.GetSymbolNamed(identifier).IsConstant {
-		cc0_error.ReportLineAndColumn(currentLine, currentColumn)
-		cc0_error.PrintfToStdErr("Cannot assign a new value to the constant: %s\n", identifier)
-		cc0_error.ThrowAndExit(cc0_error.Analyzer)
-	}
-	address := currentSymbolTable.GetAddressOf(identifier)
-	if next, err := getNextToken(); err != nil || next.Kind != token.AssignmentSign {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.IncompleteExpression).On(currentLine, currentColumn)
-	}
-	currentFunction.Append(instruction.Loada, currentSymbolTable.GetLevelDiff(identifier), address)
-	kind, anotherErr := analyzeExpression()
-	if currentVariableKind := currentSymbolTable.GetSymbolNamed(identifier).Kind; kind != currentVariableKind {
-		convertType(kind, currentSymbolTable.GetSymbolNamed(identifier).Kind)
-		kind = currentVariableKind
-	}
-	if anotherErr != nil {
-		resetHeadTo(pos)
-		return anotherErr
+// Reads what follows the identifier which has been read: the arguments of a call, the subscripts of an array or the
+// accesses to the members of a struct. What the identifier is is left for the name resolution.
+func (a *analyzer) parseOperand(identifier *Token) (ast.Expr, *Error) {
+	name := identOf(identifier)
+	next, err := a.peekNextToken()
+	if err != nil {
+		return name, nil
 	}
-	if kind == token.Double {
-		currentFunction.Append(instruction.Dstore)
-	} else {
-		currentFunction.Append(instruction.Istore)
+	switch next.Kind {
+	case token.LeftParenthesis:
+		return a.parseFunctionCall(name)
+	case token.LeftSquareBracket:
+		indices, rbrack, err := a.parseSubscripts()
+		if err != nil {
+			return nil, err
+		}
+		// The elements cannot be structs, which is reported by the name resolution rather than as a syntax error
+		return a.parseMemberAccess(&ast.IndexExpr{Array: name, Indices: indices, Rbrack: rbrack})
+	case token.Dot:
+		return a.parseMemberAccess(name)
 	}
-	return nil
+	return name, nil
 }
 
-var currentFnTotalParams = 0
-var currentDeclaredCount = 0
-
-func analyzeExpressionList(fn *instruction.Fn) *Error {
-	// <expression-list> ::= <expression>{','<expression>}
-	currentDeclaredCount = 0
-	currentFnTotalParams = len(*fn.Parameters)
-
-	pos := getCurrentPos()
-	kind, err := analyzeExpression()
-	if err != nil {
-		resetHeadTo(pos)
-		return err
+func (a *analyzer) parseMemberAccess(x ast.Expr) (ast.Expr, *Error) {
+	// <member-access> ::= <identifier>[<subscripts>]{'.' <identifier>}
+	for {
+		if _, ok := a.accept(token.Dot); !ok {
+			return x, nil
+		}
+		member, err := a.expect(token.Identifier, cc0_error.Of(cc0_error.IllegalMemberAccess).WithMessage(
+			"Expected the name of a member after '.'."))
+		if err != nil {
+			return nil, err
+		}
+		x = &ast.MemberExpr{X: x, Member: identOf(member)}
 	}
-	currentDeclaredCount++
-	if currentDeclaredCount > currentFnTotalParams {
-		return cc0_error.Of(cc0_error.IllegalExpression).On(currentLine, currentColumn)
+}
+
+func (a *analyzer) parseFunctionCall(fun *ast.Ident) (ast.Expr, *Error) {
+	// <function-call> ::= <identifier> '(' [<expression-list>] ')'
+	if _, err := a.expect(token.LeftParenthesis, cc0_error.Of(cc0_error.IncompleteFunctionCall)); err != nil {
+		return nil, err
 	}
-	if paramKind := fn.RelatedSymbolTable.GetSymbolNamed((*fn.Parameters)[currentDeclaredCount-1]).Kind; kind != paramKind {
-		convertType(kind, paramKind)
+	call := &ast.CallExpr{Fun: fun}
+	if rparen, ok := a.accept(token.RightParenthesis); ok {
+		call.Rparen = ast.EndOf(rparen)
+		return call, nil
 	}
 
+	// <expression-list> ::= <expression>{','<expression>}
 	for {
-		pos = getCurrentPos()
-		if next, err := getNextToken(); err != nil || next.Kind != token.Comma {
-			resetHeadTo(pos)
-			if currentDeclaredCount != currentFnTotalParams {
-				return cc0_error.Of(cc0_error.IllegalExpression).On(currentLine, currentColumn)
-			}
-			return nil
-		}
-		anotherKind, err := analyzeExpression()
+		arg, err := a.parseExpression()
 		if err != nil {
-			return err
-		}
-		currentDeclaredCount++
-		if currentDeclaredCount > currentFnTotalParams {
-			return cc0_error.Of(cc0_error.IllegalExpression).On(currentLine, currentColumn)
+			return nil, err
 		}
-		if paramKind := fn.RelatedSymbolTable.GetSymbolNamed((*fn.Parameters)[currentDeclaredCount-1]).Kind; anotherKind != paramKind {
-			convertType(anotherKind, paramKind)
+		call.Args = append(call.Args, arg)
+		if _, ok := a.accept(token.Comma); !ok {
+			break
 		}
 	}
-}
 
-func analyzeFunctionCall() *Error {
-	// <identifier> '(' [<expression-list>] ')'
-	pos := getCurrentPos()
-	next, err := getNextToken()
+	rparen, err := a.expect(token.RightParenthesis, cc0_error.Of(cc0_error.IncompleteFunctionCall))
 	if err != nil {
-		return cc0_error.Of(cc0_error.IncompleteFunctionCall).On(currentLine, currentColumn)
+		return nil, err
 	}
-	if next.Kind != token.Identifier {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.IncompleteFunctionCall).On(currentLine, currentColumn)
+	call.Rparen = ast.EndOf(rparen)
+	return call, nil
+}
+
+// Reads an assignment, or also a function call if `allowsFunctionCalls`.
+func (a *analyzer) parseAssignmentExpression(allowsFunctionCalls bool) (ast.Expr, *Error) {
+	// <assignment-expression> ::= (<identifier>[<subscripts>]|<member-access>)<assignment-operator><expression>
+	next, err := a.getNextToken()
+	if err != nil || next.Kind != token.Identifier {
+		return nil, cc0_error.Of(cc0_error.IncompleteExpression).On(a.currentLine, a.currentColumn)
 	}
-	identifier := next.Value.(string)
-	sb := globalSymbolTable.GetSymbolNamed(identifier)
-	if sb == nil {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.UndefinedIdentifier).On(currentLine, currentColumn)
+	lhs, operandErr := a.parseOperand(next)
+	if operandErr != nil {
+		return nil, operandErr
 	}
-	if next, err := getNextToken(); err != nil || next.Kind != token.LeftParenthesis {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.IncompleteFunctionCall).On(currentLine, currentColumn)
+	if call, ok := lhs.(*ast.CallExpr); ok {
+		if !allowsFunctionCalls {
+			return nil, cc0_error.Of(cc0_error.IncompleteExpression).On(call.Pos().Line, call.Pos().Column).
+				Until(call.End().Line, call.End().Column)
+		}
+		return call, nil
+	}
+
+	// A single identifier followed by something else is taken as a function call without its arguments
+	code := cc0_error.IncompleteExpression
+	if _, ok := lhs.(*ast.Ident); ok && allowsFunctionCalls {
+		code = cc0_error.IncompleteFunctionCall
 	}
-	_ = analyzeExpressionList(sb.FnInfo)
-	if next, err := getNextToken(); err != nil || next.Kind != token.RightParenthesis {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.IncompleteFunctionCall).On(currentLine, currentColumn)
+	assign, assignErr := a.expect(token.AssignmentSign, cc0_error.Of(code))
+	if assignErr != nil {
+		return nil, assignErr
 	}
-	currentFunction.Append(instruction.Call, sb.Address)
-	if currentDeclaredCount != currentFnTotalParams {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.IllegalExpression).On(currentLine, currentColumn)
+	rhs, rhsErr := a.parseExpression()
+	if rhsErr != nil {
+		return nil, rhsErr
 	}
-	return nil
+	return &ast.AssignExpr{Lhs: lhs, OpPos: ast.PositionOf(assign), Rhs: rhs}, nil
 }
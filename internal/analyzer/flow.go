@@ -0,0 +1,141 @@
+package analyzer
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/token"
+)
+
+// The flow analysis finds the statements which can never be executed, and whether the end of a function can be
+// reached, from how the statements are nested. The conditions are taken as unknown, except that a loop whose
+// condition is a constant which stands can only be left by a 'break'.
+type flowAnalysis struct {
+	*analyzer
+
+	// The loops and the switches enclosing the statement being analyzed, the innermost one is the last
+	targets []*flowTarget
+}
+
+// Where a 'break' or a 'continue' goes.
+type flowTarget struct {
+	isALoop bool
+
+	// Whether a 'break' or a 'continue' which can be executed goes to the target
+	broken, continued bool
+}
+
+func newFlowAnalysis(a *analyzer) *flowAnalysis {
+	return &flowAnalysis{analyzer: a}
+}
+
+// Warns about the statements after which the function can never go on, and returns whether the end of the function
+// can be reached.
+func (f *flowAnalysis) analyzeFunctionBody(body *ast.BlockStmt) bool {
+	return f.analyzeStatements(body.Stmts, true)
+}
+
+// Returns whether the end of the statements can be reached, given whether their start can. Only the first statement
+// which can never be executed is warned about, and nothing is warned about if the start cannot be reached, which
+// has been warned about by the statements around.
+func (f *flowAnalysis) analyzeStatements(stmts []ast.Stmt, reachable bool) bool {
+	warns := reachable
+	for _, stmt := range stmts {
+		if !reachable && warns && !isEmptyOrBad(stmt) {
+			f.warnOn(cc0_error.WarnUnreachable, stmt, cc0_error.Of(cc0_error.UnreachableCode).WithMessage(
+				"The statement can never be executed."))
+			warns = false
+		}
+		reachable = f.analyzeStatement(stmt, reachable)
+	}
+	return reachable
+}
+
+// Whether the statement does nothing, or has been dropped for its errors, which is not worth a warning.
+func isEmptyOrBad(stmt ast.Stmt) bool {
+	switch stmt.(type) {
+	case *ast.EmptyStmt, *ast.BadStmt:
+		return true
+	}
+	return false
+}
+
+func (f *flowAnalysis) analyzeStatement(stmt ast.Stmt, reachable bool) bool {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		return f.analyzeStatements(s.Stmts, reachable)
+	case *ast.IfStmt:
+		thenReachable := f.analyzeStatement(s.Then, reachable)
+		if s.Else == nil {
+			return thenReachable || reachable
+		}
+		return f.analyzeStatement(s.Else, reachable) || thenReachable
+	case *ast.WhileStmt:
+		target := f.enter(true)
+		f.analyzeStatement(s.Body, reachable)
+		f.leave()
+		return target.broken || (reachable && !alwaysStands(s.Cond))
+	case *ast.DoWhileStmt:
+		target := f.enter(true)
+		condReachable := f.analyzeStatement(s.Body, reachable) || target.continued
+		f.leave()
+		return target.broken || (condReachable && !alwaysStands(s.Cond))
+	case *ast.ForStmt:
+		target := f.enter(true)
+		f.analyzeStatement(s.Body, reachable)
+		f.leave()
+		return target.broken || (reachable && s.Cond != nil && !alwaysStands(s.Cond))
+	case *ast.SwitchStmt:
+		return f.analyzeSwitchStatement(s, reachable)
+	case *ast.BranchStmt:
+		return f.analyzeBranchStatement(s, reachable)
+	case *ast.ReturnStmt:
+		return false
+	}
+	return reachable
+}
+
+// Each of the clauses can be jumped to, and falls through to the next one. The end of the switch is reached without
+// any of them when there is no default label.
+func (f *flowAnalysis) analyzeSwitchStatement(s *ast.SwitchStmt, reachable bool) bool {
+	target := f.enter(false)
+	hasADefault, clauseReachable := false, false
+	for _, clause := range s.Clauses {
+		hasADefault = hasADefault || clause.IsDefault
+		clauseReachable = f.analyzeStatements(clause.Body, reachable || clauseReachable)
+	}
+	f.leave()
+	return clauseReachable || target.broken || (reachable && !hasADefault)
+}
+
+// Returns whether the statement after the branch can be reached, which is only when the branch has nowhere to go. The
+// misplaced ones have been reported by the type checking, so the statements after them are not warned about as well.
+func (f *flowAnalysis) analyzeBranchStatement(s *ast.BranchStmt, reachable bool) bool {
+	for index := len(f.targets) - 1; index >= 0; index-- {
+		target := f.targets[index]
+		if s.Kind == token.Break {
+			target.broken = target.broken || reachable
+			return false
+		}
+		if target.isALoop {
+			target.continued = target.continued || reachable
+			return false
+		}
+	}
+	return reachable
+}
+
+func (f *flowAnalysis) enter(isALoop bool) *flowTarget {
+	target := &flowTarget{isALoop: isALoop}
+	f.targets = append(f.targets, target)
+	return target
+}
+
+func (f *flowAnalysis) leave() {
+	f.targets = f.targets[:len(f.targets)-1]
+}
+
+// Whether the condition is a constant which stands, e.g. `1` of `while (1)`.
+func alwaysStands(cond ast.Expr) bool {
+	value, ok := evaluate(cond)
+	return ok && value.stands()
+}
@@ -1,168 +1,104 @@
 package analyzer
 
 import (
+	"c0_compiler/internal/ast"
 	"c0_compiler/internal/cc0_error"
-	"c0_compiler/internal/instruction"
 	"c0_compiler/internal/token"
 )
 
-func analyzeFunctionDefinitions() *Error {
+// Returns the functions parsed so far along with the error, including the incomplete one which has the error.
+func (a *analyzer) parseFunctionDefinitions() (decls []ast.Decl, err *Error) {
 	for {
-		pos := getCurrentPos()
-		next, err := getNextToken()
-		resetHeadTo(pos)
-		if err != nil || !next.IsATypeSpecifier() {
-			return nil
+		pos := a.getCurrentPos()
+		next, readErr := a.peekNextToken()
+		if readErr != nil || (!next.IsATypeSpecifier() && next.Kind != token.Struct) {
+			return
 		}
-		if err := analyzeFunctionDefinition(); err != nil {
-			resetHeadTo(pos)
-			return err
+		decl, err := a.parseFunctionDefinition()
+		if decl != nil {
+			decls = append(decls, decl)
+		}
+		if err != nil {
+			a.resetHeadTo(pos)
+			return decls, err
 		}
 	}
 }
 
-func analyzeFunctionDefinition() *Error {
-	// <function-definition> ::= <type-specifier><identifier><parameter-clause><compound-statement>
+// Once the name is read, the function is returned even if it is not complete, so that the calls to it are not
+// reported as well.
+func (a *analyzer) parseFunctionDefinition() (*ast.FuncDecl, *Error) {
+	// <function-definition> ::= <type-specifier><identifier><parameter-clause>(<compound-statement>|';')
+	// where the ';' ends a prototype, which declares the function to be defined later
 
-	pos := getCurrentPos()
-	next, err := getNextToken()
-	if err != nil || !next.IsATypeSpecifier() {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidDeclaration).On(currentLine, currentColumn)
-	}
-	kind := next.Kind
-	currentFunction = instruction.InitFn(kind)
-	currentSymbolTable = currentSymbolTable.AppendChildSymbolTable(currentFunction)
-
-	next, err = getNextToken()
-	if err != nil || next.Kind != token.Identifier {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidDeclaration).On(currentLine, currentColumn)
-	}
-	identifier := next.Value.(string)
-	if err := globalSymbolTable.AddAFunction(identifier, kind, currentFunction); err != nil {
-		resetHeadTo(pos)
-		return err
+	returnType, err := a.parseTypeSpecifier()
+	if err != nil {
+		return nil, err
 	}
-	if err := analyzeParameterClause(); err != nil {
-		resetHeadTo(pos)
-		return err
+	name, err := a.expect(token.Identifier, cc0_error.Of(cc0_error.InvalidDeclaration))
+	if err != nil {
+		return nil, err
 	}
-	if err := analyzeCompoundStatement(); err != nil {
-		resetHeadTo(pos)
-		return err
+	decl := &ast.FuncDecl{ReturnType: returnType, Name: identOf(name)}
+	if decl.Params, decl.Rparen, err = a.parseParameterClause(); err != nil {
+		return decl, err
 	}
-
-	switch currentFunction.ReturnType {
-	case token.Void:
-		currentFunction.Append(instruction.Ret)
-	case token.Int, token.Char:
-		currentFunction.Append(instruction.Ipush, 0)
-		currentFunction.Append(instruction.Iret)
-	case token.Double:
-		currentFunction.Append(instruction.Snew, 2)
-		currentFunction.Append(instruction.Dret)
+	if semicolon, ok := a.accept(token.Semicolon); ok {
+		decl.IsAPrototype, decl.Semicolon = true, ast.EndOf(semicolon)
+		return decl, nil
 	}
-
-	if funSymbol := globalSymbolTable.GetSymbolNamed(identifier); funSymbol != nil {
-		// Have to do the assignment this way thanks to all the trivia of golang
-		funSymbol.FnInfo = currentFunction
-		globalSymbolTable.Symbols[identifier] = funSymbol
+	body, err := a.parseCompoundStatement()
+	if err != nil {
+		return decl, err
 	}
-
-	currentFunction = globalStart
-	currentSymbolTable = globalSymbolTable
-	return nil
+	decl.Body = body
+	return decl, nil
 }
 
-func analyzeParameterClause() *Error {
+// Returns the parameters along with where the ')' ends.
+func (a *analyzer) parseParameterClause() (params []*ast.Param, rparen ast.Position, err *Error) {
 	// <parameter-clause> ::= '(' [<parameter-declaration-list>] ')'
-	pos := getCurrentPos()
-	next, err := getNextToken()
-	if err != nil || next.Kind != token.LeftParenthesis {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidDeclaration).On(currentLine, currentColumn)
-	}
-
-	next, err = getNextToken()
-	if err != nil {
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+	if _, err := a.expect(token.LeftParenthesis, cc0_error.Of(cc0_error.InvalidDeclaration)); err != nil {
+		return nil, rparen, err
 	}
-	if next.Kind == token.RightParenthesis {
-		return nil
+	if next, ok := a.accept(token.RightParenthesis); ok {
+		return nil, ast.EndOf(next), nil
 	}
 
-	// Put back the token previously read in
-	resetHeadTo(pos + 1)
-
-	if err := analyzeParameterDeclarationList(); err != nil {
-		resetHeadTo(pos)
-		return err
-	}
-	next, err = getNextToken()
-	if err != nil || next.Kind != token.RightParenthesis {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.IncompleteExpression)
-	}
-
-	return nil
-}
-
-func analyzeParameterDeclarationList() *Error {
 	// <parameter-declaration-list> ::= <parameter-declaration>{','<parameter-declaration>}
-
-	// <parameter-declaration>
-	if err := analyzeParameterDeclaration(); err != nil {
-		return err
-	}
-
-	// {','<parameter-declaration>}
 	for {
-		pos := getCurrentPos()
-		next, err := getNextToken()
+		param, err := a.parseParameterDeclaration()
 		if err != nil {
-			return nil
+			return params, rparen, err
 		}
-		if next.Kind != token.Comma {
-			resetHeadTo(pos)
-			return nil
-		}
-		if err := analyzeParameterDeclaration(); err != nil {
-			resetHeadTo(pos)
-			return err
+		params = append(params, param)
+		if _, ok := a.accept(token.Comma); !ok {
+			break
 		}
 	}
-}
 
-func analyzeParameterDeclaration() *Error {
-	// [<const-qualifier>]<type-specifier><identifier>
-	next, err := getNextToken()
+	next, err := a.expect(token.RightParenthesis, cc0_error.Of(cc0_error.IncompleteExpression))
 	if err != nil {
-		return cc0_error.Of(cc0_error.InvalidDeclaration).On(currentLine, currentColumn)
+		return params, rparen, err
 	}
-	isConst := false
-	if next.Kind == token.Const {
-		isConst = true
-		next, err = getNextToken()
-		if err != nil {
-			return cc0_error.Of(cc0_error.InvalidDeclaration).On(currentLine, currentColumn)
-		}
-	}
-	if !next.IsATypeSpecifier() || next.Kind == token.Void {
-		return cc0_error.Of(cc0_error.InvalidDeclaration).On(currentLine, currentColumn)
+	return params, ast.EndOf(next), nil
+}
+
+func (a *analyzer) parseParameterDeclaration() (*ast.Param, *Error) {
+	// <parameter-declaration> ::= [<const-qualifier>]<type-specifier><identifier>
+	param := &ast.Param{}
+	if next, ok := a.accept(token.Const); ok {
+		param.IsConstant, param.Const = true, ast.PositionOf(next)
 	}
-	kind := next.Kind
-	next, err = getNextToken()
-	if err != nil || next.Kind != token.Identifier {
-		return cc0_error.Of(cc0_error.InvalidDeclaration).On(currentLine, currentColumn)
+	typeSpec, err := a.parseTypeSpecifier()
+	if err != nil {
+		return nil, err
 	}
-	identifier := next.Value.(string)
-
-	if isConst {
-		_ = currentSymbolTable.AddAConstant(identifier, kind)
-	} else {
-		_ = currentSymbolTable.AddAVariable(identifier, kind)
+	param.Type = typeSpec
+	name, err := a.expect(token.Identifier, cc0_error.Of(cc0_error.InvalidDeclaration))
+	if err != nil {
+		return nil, err
 	}
-	*currentFunction.Parameters = append(*currentFunction.Parameters, identifier)
-	return nil
+	param.Name = identOf(name)
+	return param, nil
 }
@@ -1,140 +1,70 @@
 package analyzer
 
 import (
+	"c0_compiler/internal/ast"
 	"c0_compiler/internal/cc0_error"
-	"c0_compiler/internal/instruction"
 	"c0_compiler/internal/token"
 )
 
-func analyzeIOStatement() *Error {
-	// <scan-statement>  ::= 'scan' '(' <identifier> ')' ';'
+func (a *analyzer) parseIOStatement() (ast.Stmt, *Error) {
+	// <scan-statement>  ::= 'scan' '(' (<identifier>[<subscripts>]|<member-access>) ')' ';'
 	// <print-statement> ::= 'print' '(' [<printable-list>] ')' ';'
-	pos := getCurrentPos()
-	next, err := getNextToken()
-	if err != nil {
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+	keyword, err := a.getNextToken()
+	if err != nil || (keyword.Kind != token.Scan && keyword.Kind != token.Print) {
+		return nil, cc0_error.Of(cc0_error.InvalidStatement).On(a.currentLine, a.currentColumn)
+	}
+	if _, err := a.expect(token.LeftParenthesis, cc0_error.Of(cc0_error.InvalidStatement)); err != nil {
+		return nil, err
 	}
-	if next.Kind == token.Scan {
-		if next, err := getNextToken(); err != nil || next.Kind != token.LeftParenthesis {
-			resetHeadTo(pos)
-			return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
-		}
-		next, err = getNextToken()
-		if err != nil || next.Kind != token.Identifier {
-			resetHeadTo(pos)
-			return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
-		}
-		identifier := next.Value.(string)
-		if symb := currentSymbolTable.GetSymbolNamed(identifier); symb == nil || symb.IsConstant {
-			resetHeadTo(pos)
-			return cc0_error.Of(cc0_error.IllegalExpression)
-		}
-		if next, err := getNextToken(); err != nil || next.Kind != token.RightParenthesis {
-			resetHeadTo(pos)
-			return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
-		}
-		currentFunction.Append(instruction.Loada, currentSymbolTable.GetLevelDiff(identifier), currentSymbolTable.GetAddressOf(identifier))
-		targetKind := currentSymbolTable.GetSymbolNamed(identifier).Kind
-		switch targetKind {
-		case token.Int:
-			currentFunction.Append(instruction.Iscan)
-			currentFunction.Append(instruction.Istore)
-		case token.Char:
-			currentFunction.Append(instruction.Cscan)
-			currentFunction.Append(instruction.Istore)
-		case token.Double:
-			currentFunction.Append(instruction.Dscan)
-			currentFunction.Append(instruction.Dstore)
-		}
 
-	} else if next.Kind == token.Print {
-		if next, err := getNextToken(); err != nil || next.Kind != token.LeftParenthesis {
-			resetHeadTo(pos)
-			return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
-		}
-		pos := getCurrentPos()
-		preReadNext, err := getNextToken()
+	var target ast.Expr
+	var args []ast.Expr
+	if keyword.Kind == token.Scan {
+		identifier, err := a.expect(token.Identifier, cc0_error.Of(cc0_error.InvalidStatement))
 		if err != nil {
-			resetHeadTo(pos)
-			return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+			return nil, err
+		}
+		if target, err = a.parseOperand(identifier); err != nil {
+			return nil, err
 		}
-		if preReadNext.Kind != token.RightParenthesis {
-			resetHeadTo(pos)
-			if err := analyzePrintableList(); err != nil {
-				resetHeadTo(pos)
-				return err
-			}
-			if next, err := getNextToken(); err != nil || next.Kind != token.RightParenthesis {
-				resetHeadTo(pos)
-				return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
-			}
+	} else if next, err := a.peekNextToken(); err == nil && next.Kind != token.RightParenthesis {
+		var listErr *Error
+		if args, listErr = a.parsePrintableList(); listErr != nil {
+			return nil, listErr
 		}
-		currentFunction.Append(instruction.Printl)
-	} else {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
 	}
-	if next, err := getNextToken(); err != nil || next.Kind != token.Semicolon {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+	if _, err := a.expect(token.RightParenthesis, cc0_error.Of(cc0_error.InvalidStatement)); err != nil {
+		return nil, err
 	}
-	return nil
+	semicolon, semicolonErr := a.expect(token.Semicolon, cc0_error.Of(cc0_error.InvalidStatement))
+	if semicolonErr != nil {
+		return nil, semicolonErr
+	}
+
+	if keyword.Kind == token.Scan {
+		return &ast.ScanStmt{Scan: ast.PositionOf(keyword), Target: target, Semicolon: ast.EndOf(semicolon)}, nil
+	}
+	return &ast.PrintStmt{Print: ast.PositionOf(keyword), Args: args, Semicolon: ast.EndOf(semicolon)}, nil
 }
 
-func analyzePrintableList() *Error {
+func (a *analyzer) parsePrintableList() (list []ast.Expr, err *Error) {
 	// <printable-list>  ::= <printable> {',' <printable>}
-	pos := getCurrentPos()
-	if err := analyzePrintable(); err != nil {
-		resetHeadTo(pos)
-		return err
-	}
 	for {
-		pos = getCurrentPos()
-		if next, err := getNextToken(); err != nil || next.Kind != token.Comma {
-			resetHeadTo(pos)
-			return nil
+		printable, err := a.parsePrintable()
+		if err != nil {
+			return nil, err
 		}
-		currentFunction.Append(instruction.Bipush, 32)
-		currentFunction.Append(instruction.Cprint)
-		if err := analyzePrintable(); err != nil {
-			return err
+		list = append(list, printable)
+		if _, ok := a.accept(token.Comma); !ok {
+			return list, nil
 		}
 	}
 }
 
-func analyzePrintable() *Error {
+func (a *analyzer) parsePrintable() (ast.Expr, *Error) {
 	// <printable> ::= <expression> | <string-literal> | <char-literal>
-	pos := getCurrentPos()
-	kind, err := analyzeExpression()
-	if err != nil {
-		resetHeadTo(pos)
-	} else {
-		switch kind {
-		case token.Double:
-			currentFunction.Append(instruction.Dprint)
-		case token.Char:
-			currentFunction.Append(instruction.Cprint)
-		case token.Void:
-			return cc0_error.Of(cc0_error.InvalidStatement)
-		default:
-			currentFunction.Append(instruction.Iprint)
-		}
-		return nil
-	}
-	next, anotherErr := getNextToken()
-	if anotherErr != nil {
-		return cc0_error.Of(cc0_error.IncompleteExpression)
-	}
-	if next.Kind == token.StringLiteral {
-		address := globalSymbolTable.AddALiteral(instruction.ConstantKindString, next.Value.(string))
-		currentFunction.Append(instruction.Loadc, -address)
-		currentFunction.Append(instruction.Sprint)
-		return nil
-	} else if next.Kind == token.CharLiteral {
-		currentFunction.Append(instruction.Bipush, int(next.Value.(int32)))
-		currentFunction.Append(instruction.Cprint)
-	} else {
-		return cc0_error.Of(cc0_error.IncompleteExpression)
+	if next, ok := a.accept(token.StringLiteral); ok {
+		return literalOf(next), nil
 	}
-	return nil
+	return a.parseExpression()
 }
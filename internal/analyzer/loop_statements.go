@@ -1,41 +1,198 @@
 package analyzer
 
 import (
+	"c0_compiler/internal/ast"
 	"c0_compiler/internal/cc0_error"
-	"c0_compiler/internal/instruction"
 	"c0_compiler/internal/token"
 )
 
-func analyzeLoopStatement() *Error {
-	// <loop-statement> ::= 'while' '(' <condition> ')' <statement>
+func (a *analyzer) parseLoopStatement() (ast.Stmt, *Error) {
+	// <loop-statement> ::=
+	//		'while' '(' <condition> ')' <statement>
+	//		|'do' <statement> 'while' '(' <condition> ')' ';'
+	//		|'for' '(' <for-init-statement> [<condition>] ';' [<for-update-expression>] ')' <statement>
 
-	pos := getCurrentPos()
-	next, err := getNextToken()
-	if err != nil || next.Kind != token.While {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+	next, err := a.peekNextToken()
+	if err != nil {
+		return nil, cc0_error.Of(cc0_error.InvalidStatement).On(a.currentLine, a.currentColumn)
 	}
-	next, err = getNextToken()
-	if err != nil || next.Kind != token.LeftParenthesis {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+	switch next.Kind {
+	case token.While:
+		return a.parseWhileStatement()
+	case token.Do:
+		return a.parseDoWhileStatement()
+	case token.For:
+		return a.parseForStatement()
 	}
-	offsetBeforeConditionEvaluation := currentFunction.GetCurrentOffset()
-	if err := analyzeCondition(); err != nil {
-		resetHeadTo(pos)
+	return nil, cc0_error.Of(cc0_error.InvalidStatement).On(next.Line, next.Column)
+}
+
+func (a *analyzer) parseWhileStatement() (ast.Stmt, *Error) {
+	// 'while' '(' <condition> ')' <statement>
+
+	whileToken, err := a.expect(token.While, cc0_error.Of(cc0_error.InvalidStatement))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.expect(token.LeftParenthesis, cc0_error.Of(cc0_error.InvalidStatement)); err != nil {
+		return nil, err
+	}
+	cond, err := a.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.expect(token.RightParenthesis, cc0_error.Of(cc0_error.InvalidStatement)); err != nil {
+		return nil, err
+	}
+	body, err := a.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.WhileStmt{While: ast.PositionOf(whileToken), Cond: cond, Body: body}, nil
+}
+
+func (a *analyzer) parseDoWhileStatement() (ast.Stmt, *Error) {
+	// 'do' <statement> 'while' '(' <condition> ')' ';'
+
+	doToken, err := a.expect(token.Do, cc0_error.Of(cc0_error.InvalidStatement))
+	if err != nil {
+		return nil, err
+	}
+	body, err := a.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.expect(token.While, cc0_error.Of(cc0_error.InvalidStatement).WithMessage(
+		"Expected a 'while' after the body of the 'do' statement.")); err != nil {
+		return nil, err
+	}
+	if _, err := a.expect(token.LeftParenthesis, cc0_error.Of(cc0_error.InvalidStatement)); err != nil {
+		return nil, err
+	}
+	cond, err := a.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.expect(token.RightParenthesis, cc0_error.Of(cc0_error.InvalidStatement)); err != nil {
+		return nil, err
+	}
+	semicolon, err := a.expect(token.Semicolon, cc0_error.Of(cc0_error.InvalidStatement).WithMessage(
+		"Expected a ';' at the end of the statement."))
+	if err != nil {
+		return nil, err
+	}
+	return &ast.DoWhileStmt{Do: ast.PositionOf(doToken), Body: body, Cond: cond, Semicolon: ast.EndOf(semicolon)}, nil
+}
+
+func (a *analyzer) parseForStatement() (ast.Stmt, *Error) {
+	// 'for' '(' <for-init-statement> [<condition>] ';' [<for-update-expression>] ')' <statement>
+
+	forToken, err := a.expect(token.For, cc0_error.Of(cc0_error.InvalidStatement))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.expect(token.LeftParenthesis, cc0_error.Of(cc0_error.InvalidStatement)); err != nil {
+		return nil, err
+	}
+	stmt := &ast.ForStmt{For: ast.PositionOf(forToken)}
+	posOfHeader := a.getCurrentPos()
+	if err := a.parseForHeader(stmt); err != nil {
+		return a.recoverFromForHeader(stmt, err, posOfHeader)
+	}
+	if stmt.Body, err = a.parseStatement(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// Reads the parts in the parentheses of a 'for' statement along with the closing ')'.
+func (a *analyzer) parseForHeader(stmt *ast.ForStmt) (err *Error) {
+	// <for-init-statement> ::= [<assignment-expression>{','<assignment-expression>}]';'
+	if next, readErr := a.peekNextToken(); readErr == nil && next.Kind != token.Semicolon {
+		if stmt.Init, err = a.parseForExpressionList(false); err != nil {
+			return err
+		}
+	}
+	if _, err := a.expect(token.Semicolon, cc0_error.Of(cc0_error.InvalidStatement).WithMessage(
+		"Expected a ';' after the initialization of the 'for' statement.")); err != nil {
 		return err
 	}
-	conditionLine := currentFunction.GetCurrentLine()
 
-	next, err = getNextToken()
-	if err != nil || next.Kind != token.RightParenthesis {
-		resetHeadTo(pos)
-		return cc0_error.Of(cc0_error.InvalidStatement).On(currentLine, currentColumn)
+	if next, readErr := a.peekNextToken(); readErr == nil && next.Kind != token.Semicolon {
+		if stmt.Cond, err = a.parseCondition(); err != nil {
+			return err
+		}
 	}
-	if err := analyzeStatement(); err != nil {
+	if _, err := a.expect(token.Semicolon, cc0_error.Of(cc0_error.InvalidStatement).WithMessage(
+		"Expected a ';' after the condition of the 'for' statement.")); err != nil {
 		return err
 	}
-	currentFunction.Append(instruction.Jmp, offsetBeforeConditionEvaluation)
-	conditionLine.SetFirstOperandTo(currentFunction.GetCurrentOffset())
-	return nil
+
+	if next, readErr := a.peekNextToken(); readErr == nil && next.Kind != token.RightParenthesis {
+		if stmt.Update, err = a.parseForExpressionList(true); err != nil {
+			return err
+		}
+	}
+	_, err = a.expect(token.RightParenthesis, cc0_error.Of(cc0_error.InvalidStatement).WithMessage(
+		"Expected a ')' to close the '(' of the 'for' statement."))
+	return err
+}
+
+// <for-init-statement> allows only assignments while <for-update-expression> allows function calls as well:
+// <for-update-expression> ::= (<assignment-expression>|<function-call>){','(<assignment-expression>|<function-call>)}
+func (a *analyzer) parseForExpressionList(allowsFunctionCalls bool) (list []ast.Expr, err *Error) {
+	for {
+		x, err := a.parseAssignmentExpression(allowsFunctionCalls)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, x)
+		if _, ok := a.accept(token.Comma); !ok {
+			return list, nil
+		}
+	}
+}
+
+// Moves the head to the ')' matching the '(' which has been read.
+func (a *analyzer) skipToClosingParenthesis() *Error {
+	depth := 0
+	for {
+		pos := a.getCurrentPos()
+		next, err := a.getNextToken()
+		if err != nil {
+			return cc0_error.Of(cc0_error.InvalidStatement).On(a.currentLine, a.currentColumn).WithMessage(
+				"Expected a ')' to close the '(' of the 'for' statement.")
+		}
+		switch next.Kind {
+		case token.LeftParenthesis:
+			depth++
+		case token.RightParenthesis:
+			if depth == 0 {
+				a.resetHeadTo(pos)
+				return nil
+			}
+			depth--
+		case token.LeftBracket, token.RightBracket:
+			a.resetHeadTo(pos)
+			return cc0_error.Of(cc0_error.InvalidStatement).On(next.Line, next.Column).WithMessage(
+				"Expected a ')' to close the '(' of the 'for' statement.")
+		}
+	}
+}
+
+// Reports the error in the parentheses of a 'for' statement and goes on with its body, so that the errors in the body
+// are found as well. The parts of the header with errors are left out of the statement.
+func (a *analyzer) recoverFromForHeader(stmt *ast.ForStmt, err *Error, posOfHeader int) (ast.Stmt, *Error) {
+	a.report(err)
+	a.resetHeadTo(posOfHeader)
+	if a.skipToClosingParenthesis() != nil {
+		return &ast.EmptyStmt{Semicolon: stmt.For}, nil
+	}
+	_, _ = a.getNextToken()
+	body, bodyErr := a.parseStatement()
+	if bodyErr != nil {
+		return nil, bodyErr
+	}
+	stmt.Body = body
+	return stmt, nil
 }
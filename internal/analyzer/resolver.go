@@ -0,0 +1,449 @@
+package analyzer
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/token"
+	"math"
+)
+
+// The name resolution declares the symbols in the order of the source and binds the identifiers to them. A function
+// is declared by its first prototype or definition, whichever comes first, so that it can be called from then on,
+// including by itself and by the functions defined before its own definition. The problems of the declarations are reported here as well, and the symbols with them are still declared where
+// possible so that their uses are not reported again.
+type resolver struct {
+	*analyzer
+	globalSymbolTable, currentSymbolTable *SymbolTable
+	globalStart                           *instruction.Fn
+
+	// The struct types by their names, which are all declared at the global scope
+	structTypes map[string]*instruction.StructType
+
+	// A type is shared by all the declarators of a declaration, and is resolved only once
+	resolvedTypes map[*ast.TypeSpec]bool
+
+	// The symbols which the identifiers have been bound to
+	used map[*instruction.Symbol]bool
+
+	// The first declarations of the functions, which may be prototypes, and the functions which have been defined
+	functionDecls    map[*instruction.Symbol]*ast.FuncDecl
+	definedFunctions map[*instruction.Symbol]bool
+}
+
+func newResolver(a *analyzer) *resolver {
+	globalStart := instruction.InitFn(token.Void)
+	globalSymbolTable := instruction.InitSymbolTable(nil, globalStart)
+	return &resolver{
+		analyzer:           a,
+		globalSymbolTable:  globalSymbolTable,
+		currentSymbolTable: globalSymbolTable,
+		globalStart:        globalStart,
+		structTypes:        map[string]*instruction.StructType{},
+		resolvedTypes:      map[*ast.TypeSpec]bool{},
+		used:               map[*instruction.Symbol]bool{},
+		functionDecls:      map[*instruction.Symbol]*ast.FuncDecl{},
+		definedFunctions:   map[*instruction.Symbol]bool{},
+	}
+}
+
+func (r *resolver) resolveProgram(program *ast.Program) *SymbolTable {
+	for _, decl := range program.Decls {
+		if r.diagnostics.ShouldStop() {
+			break
+		}
+		switch decl := decl.(type) {
+		case *ast.StructDecl:
+			r.resolveStructDeclaration(decl)
+		case *ast.VarDecl:
+			r.resolveVariableDeclaration(decl)
+		case *ast.FuncDecl:
+			r.resolveFunctionDefinition(decl)
+		}
+	}
+	r.reportUndefinedFunctions(program)
+	return r.globalSymbolTable
+}
+
+// Fills the type of the specifier, which stays invalid if the struct is undefined.
+func (r *resolver) resolveTypeSpecifier(typeSpec *ast.TypeSpec) ast.Type {
+	if r.resolvedTypes[typeSpec] {
+		return typeSpec.Type
+	}
+	r.resolvedTypes[typeSpec] = true
+	if typeSpec.Kind != token.Struct {
+		typeSpec.Type = ast.Type{Kind: typeSpec.Kind}
+		return typeSpec.Type
+	}
+	name := typeSpec.StructName.Name
+	if structType, ok := r.structTypes[name]; ok {
+		typeSpec.Type = ast.StructOf(structType)
+	} else {
+		r.reportOn(typeSpec.StructName, cc0_error.Of(cc0_error.UndefinedIdentifier).WithMessage(
+			"Cannot use the undefined struct '%s'.", name))
+	}
+	return typeSpec.Type
+}
+
+func (r *resolver) resolveStructDeclaration(decl *ast.StructDecl) {
+	name := decl.Name.Name
+	structType := &instruction.StructType{Name: name, Line: decl.Name.NamePos.Line, Column: decl.Name.NamePos.Column}
+	for _, field := range decl.Fields {
+		t := r.resolveTypeSpecifier(field.Type)
+		if t.Kind == token.Void {
+			r.reportOn(field.Type, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+				"A member cannot be declared as void."))
+			continue
+		}
+		if err := structType.AddAMember(field.Name.Name, t.Kind, t.Struct); err != nil {
+			r.reportOn(field.Name, err.WithMessage("The member '%s' cannot be redeclared.", field.Name.Name))
+		}
+	}
+	decl.Layout = structType
+
+	if previous, ok := r.structTypes[name]; ok {
+		r.reportOn(decl.Name, cc0_error.Of(cc0_error.RedeclaredAnIdentifier).WithMessage(
+			"The struct '%s' cannot be redeclared.", name).
+			WithNote(cc0_error.Note("Previous declaration of 'struct %s' was here.", name).
+				On(previous.Line, previous.Column).Until(previous.Line, previous.Column+len(name))))
+		return
+	}
+	r.structTypes[name] = structType
+}
+
+func (r *resolver) resolveVariableDeclaration(decl *ast.VarDecl) {
+	t := r.resolveTypeSpecifier(decl.Type)
+	name := decl.Name.Name
+	table := r.currentSymbolTable
+	switch {
+	case len(decl.Dimensions) > 0:
+		r.declareArray(decl, t)
+	case t.Kind == token.Struct:
+		r.declare(table, decl.Name, table.AddAStruct(name, t.Struct))
+		if decl.Name.Symbol != nil {
+			decl.Name.Symbol.IsConstant = decl.IsConstant
+		}
+	case decl.IsConstant:
+		// Whether the constant takes a slot is known once its initializer is resolved
+		r.declare(table, decl.Name, table.AddAConstantWithoutASlot(name, t.Kind))
+		defer r.evaluateConstant(decl, t)
+	default:
+		r.declare(table, decl.Name, table.AddAVariable(name, t.Kind))
+	}
+
+	if len(decl.Dimensions) == 0 {
+		switch {
+		case t.Kind == token.Void:
+			r.reportOn(decl.Name, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+				"A variable cannot be declared as void."))
+		case decl.IsConstant && decl.Init == nil:
+			r.reportOn(decl.Name, cc0_error.Of(cc0_error.IncompleteExpression).WithMessage(
+				"The constant '%s' must be initialized.", name))
+		}
+	}
+	if decl.Init != nil {
+		r.resolveNames(decl.Init)
+	}
+}
+
+// A constant whose initializer is built from the literals and the other constants whose values are known takes no
+// slot, and its value is used in place of it.
+func (r *resolver) evaluateConstant(decl *ast.VarDecl, t ast.Type) {
+	sb := decl.Name.Symbol
+	if sb == nil {
+		return
+	}
+	if decl.Init != nil && t.IsArithmetic() {
+		if value, ok := evaluate(decl.Init); ok {
+			sb.Value = value.convertTo(t.Kind).value()
+			return
+		}
+	}
+	r.currentSymbolTable.TakeASlotFor(sb)
+}
+
+// The elements of an array are laid out in the heap one dimension after another, and only the address of the
+// elements is kept in the slot of the array. The array is declared unless its lengths are invalid, so that its uses
+// are not reported along with the other problems.
+func (r *resolver) declareArray(decl *ast.VarDecl, t ast.Type) {
+	dimensions := []int{}
+	size := int64(1)
+	for _, length := range decl.Dimensions {
+		size *= length.Value
+		if length.Value <= 0 || size > math.MaxInt32/2 {
+			r.reportOn(length, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+				"The length of an array must be positive and not too large."))
+			return
+		}
+		dimensions = append(dimensions, int(length.Value))
+	}
+
+	switch {
+	case t.Kind == token.Struct:
+		// Still declared with the type of its elements, so that the accesses to their members are not reported as well
+		r.reportOn(decl.Name, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+			"An array of structs is not supported.").WithNote(noteOnTheLimitsOfStructs()))
+	case decl.IsConstant:
+		r.reportOn(decl.Name, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+			"An array cannot be declared as a constant."))
+	case t.Kind == token.Void:
+		r.reportOn(decl.Name, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+			"An array cannot be declared as void."))
+	case decl.Init != nil:
+		r.reportOn(decl.Init, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+			"An array cannot be initialized."))
+	}
+	r.declare(r.currentSymbolTable, decl.Name, r.currentSymbolTable.AddAnArray(decl.Name.Name, t.Kind, dimensions))
+	if decl.Name.Symbol != nil {
+		decl.Name.Symbol.Struct = t.Struct
+	}
+}
+
+func (r *resolver) resolveFunctionDefinition(decl *ast.FuncDecl) {
+	returnType := r.resolveTypeSpecifier(decl.ReturnType)
+	if decl.ReturnType.Kind == token.Struct {
+		r.reportOn(decl.ReturnType, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+			"A function cannot return a struct.").WithNote(noteOnTheLimitsOfStructs()))
+		returnType = ast.Type{}
+	}
+	fn := instruction.InitFn(returnType.Kind)
+	name := decl.Name.Name
+	// A function declared before can be declared again, and defined once
+	previous := r.globalSymbolTable.Symbols[name]
+	_, isDeclared := r.functionDecls[previous]
+	redeclares := isDeclared && (decl.IsAPrototype || !r.definedFunctions[previous])
+	if !redeclares {
+		r.declare(r.globalSymbolTable, decl.Name, r.globalSymbolTable.AddAFunction(name, returnType.Kind, fn))
+		if decl.Name.Symbol != nil {
+			r.functionDecls[decl.Name.Symbol] = decl
+		}
+	}
+
+	r.currentSymbolTable = r.globalSymbolTable.AppendChildSymbolTable(fn)
+	for _, param := range decl.Params {
+		r.resolveParameter(param, fn)
+	}
+	if redeclares && r.matchesTheFirstDeclaration(decl, previous) {
+		// The function declared before is defined by this one, which has its own parameters
+		decl.Name.Symbol = previous
+		if !decl.IsAPrototype {
+			previous.FnInfo = fn
+		}
+	}
+	if redeclares && !decl.IsAPrototype {
+		// Not to be reported as undefined after the conflict
+		r.definedFunctions[previous] = true
+	}
+	if decl.Name.Symbol == nil {
+		// A redeclared function is still checked on its own
+		decl.Name.Symbol = &instruction.Symbol{FnInfo: fn, IsCallable: true, IsConstant: true, Kind: returnType.Kind,
+			Name: name}
+	}
+	if !decl.IsAPrototype {
+		r.definedFunctions[decl.Name.Symbol] = true
+	}
+	if decl.Body != nil {
+		// The parameters are in the same scope as the outermost declarations of the body
+		decl.Body.Scope = r.currentSymbolTable
+		r.resolveBlockContents(decl.Body)
+		r.warnAboutUnusedParameters(decl)
+	}
+	r.currentSymbolTable = r.globalSymbolTable
+}
+
+// Reports the difference between the signature of the function and the one of its first declaration, which must have
+// the same return type, and the same number of parameters of the same types and constness.
+func (r *resolver) matchesTheFirstDeclaration(decl *ast.FuncDecl, sb *instruction.Symbol) bool {
+	first := r.functionDecls[sb]
+	var node ast.Node
+	var err *Error
+	switch {
+	case decl.ReturnType.Type != first.ReturnType.Type:
+		node, err = decl.ReturnType, cc0_error.Of(cc0_error.ConflictingDeclaration).WithMessage(
+			"The function '%s' was declared to return %s.", sb.Name, first.ReturnType.Type)
+	case len(decl.Params) != len(first.Params):
+		node, err = decl.Name, cc0_error.Of(cc0_error.ConflictingDeclaration).WithMessage(
+			"The function '%s' was declared to take %d parameter(s).", sb.Name, len(first.Params))
+	default:
+		for index, param := range decl.Params {
+			firstParam := first.Params[index]
+			if param.Type.Type != firstParam.Type.Type || param.IsConstant != firstParam.IsConstant {
+				node, err = param, cc0_error.Of(cc0_error.ConflictingDeclaration).WithMessage(
+					"The parameter %d of the function '%s' was declared as '%s'.", index+1, sb.Name,
+					parameterTypeOf(firstParam))
+				break
+			}
+		}
+	}
+	if err == nil {
+		return true
+	}
+	r.reportOn(node, err.WithNote(r.previousDeclarationOf(sb)))
+	return false
+}
+
+func parameterTypeOf(param *ast.Param) string {
+	if param.IsConstant {
+		return "const " + param.Type.Type.String()
+	}
+	return param.Type.Type.String()
+}
+
+// The functions which are called must be defined to be assembled, and the others are most likely mistakes.
+func (r *resolver) reportUndefinedFunctions(program *ast.Program) {
+	for _, decl := range program.Decls {
+		decl, ok := decl.(*ast.FuncDecl)
+		if !ok || !decl.IsAPrototype || r.functionDecls[decl.Name.Symbol] != decl ||
+			r.definedFunctions[decl.Name.Symbol] {
+			continue
+		}
+		r.reportOn(decl.Name, cc0_error.Of(cc0_error.UndefinedFunction).WithMessage(
+			"The function '%s' is declared but never defined.", decl.Name.Name))
+	}
+}
+
+// A nested block has its own scope, whose slots are given back to the function when it ends so that the blocks after
+// it can take them again.
+func (r *resolver) resolveBlock(block *ast.BlockStmt) {
+	enclosingTable := r.currentSymbolTable
+	r.currentSymbolTable = enclosingTable.AppendChildSymbolTable(enclosingTable.RelatedFunction)
+	block.Scope = r.currentSymbolTable
+	r.resolveBlockContents(block)
+	r.currentSymbolTable.ReleaseMemorySlots()
+	r.currentSymbolTable = enclosingTable
+}
+
+func (r *resolver) resolveBlockContents(block *ast.BlockStmt) {
+	for _, varDecl := range block.Decls {
+		r.resolveVariableDeclaration(varDecl)
+	}
+	for _, stmt := range block.Stmts {
+		r.resolveNames(stmt)
+	}
+	r.warnAboutUnusedVariables(block)
+}
+
+func (r *resolver) warnAboutUnusedParameters(decl *ast.FuncDecl) {
+	for _, param := range decl.Params {
+		if sb := param.Name.Symbol; sb != nil && !r.used[sb] {
+			r.warnOn(cc0_error.WarnUnusedParameter, param.Name, cc0_error.Of(cc0_error.UnusedParameter).WithMessage(
+				"The parameter '%s' is never used.", sb.Name))
+		}
+	}
+}
+
+// The global variables are left out, since they may be there for the functions to be added later.
+func (r *resolver) warnAboutUnusedVariables(block *ast.BlockStmt) {
+	for _, varDecl := range block.Decls {
+		if sb := varDecl.Name.Symbol; sb != nil && !r.used[sb] {
+			r.warnOn(cc0_error.WarnUnusedVariable, varDecl.Name, cc0_error.Of(cc0_error.UnusedVariable).WithMessage(
+				"The variable '%s' is never used.", sb.Name))
+		}
+	}
+}
+
+func (r *resolver) resolveParameter(param *ast.Param, fn *instruction.Fn) {
+	t := r.resolveTypeSpecifier(param.Type)
+	if t.Kind == token.Void {
+		r.reportOn(param.Type, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+			"A parameter cannot be declared as void."))
+		t = ast.Type{}
+	}
+	name := param.Name.Name
+	table := r.currentSymbolTable
+	switch {
+	case t.Kind == token.Struct:
+		r.declare(table, param.Name, table.AddAStruct(name, t.Struct))
+		if param.Name.Symbol != nil {
+			param.Name.Symbol.IsConstant = param.IsConstant
+		}
+	case param.IsConstant:
+		r.declare(table, param.Name, table.AddAConstant(name, t.Kind))
+	default:
+		r.declare(table, param.Name, table.AddAVariable(name, t.Kind))
+	}
+	if param.Name.Symbol != nil {
+		*fn.Parameters = append(*fn.Parameters, name)
+	}
+}
+
+// Binds the identifiers used in the node to their symbols. The members are left for the type checking, which knows
+// the types of the structs.
+func (r *resolver) resolveNames(node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.MemberExpr:
+			r.resolveNames(n.X)
+			return false
+		case *ast.TypeSpec:
+			r.resolveTypeSpecifier(n)
+			return false
+		case *ast.BlockStmt:
+			r.resolveBlock(n)
+			return false
+		case *ast.BadStmt:
+			// The identifiers may not be what they seem in a statement with syntax errors, so nothing is reported
+			for _, name := range n.Names {
+				if sb := r.currentSymbolTable.GetSymbolNamed(name.Name); sb != nil {
+					r.used[sb] = true
+				}
+			}
+			return false
+		case *ast.Ident:
+			r.resolveIdentifier(n)
+		}
+		return true
+	})
+}
+
+func (r *resolver) resolveIdentifier(ident *ast.Ident) {
+	if ident.Symbol = r.currentSymbolTable.GetSymbolNamed(ident.Name); ident.Symbol != nil {
+		r.used[ident.Symbol] = true
+		return
+	}
+	err := cc0_error.Of(cc0_error.UndefinedIdentifier).WithMessage(
+		"Cannot use the undefined identifier '%s'.", ident.Name)
+	if similarName := r.currentSymbolTable.GetSimilarName(ident.Name); similarName != "" {
+		err.WithNote(cc0_error.Hint("Did you mean '%s'?", similarName))
+	}
+	r.reportOn(ident, err)
+}
+
+// Binds the identifier to the symbol declared for it if `err` is nil, otherwise `err` is about a redeclaration and it
+// is reported with the previous declaration attached.
+func (r *resolver) declare(table *SymbolTable, ident *ast.Ident, err *Error) {
+	if err == nil {
+		sb := table.Symbols[ident.Name]
+		sb.Line, sb.Column = ident.NamePos.Line, ident.NamePos.Column
+		ident.Symbol = sb
+		if table.Parent != nil {
+			r.warnIfShadowing(table.Parent, ident)
+		}
+		return
+	}
+	if err.Code() == cc0_error.RedeclaredAnIdentifier {
+		err.WithMessage("The identifier '%s' cannot be redeclared.", ident.Name)
+		if previous, ok := table.Symbols[ident.Name]; ok && previous.Line > 0 {
+			err.WithNote(r.previousDeclarationOf(previous))
+		}
+	}
+	r.reportOn(ident, err)
+}
+
+// Warns if the name declared hides another declaration visible from `outer`.
+func (r *resolver) warnIfShadowing(outer *SymbolTable, ident *ast.Ident) {
+	previous := outer.GetSymbolNamed(ident.Name)
+	if previous == nil {
+		return
+	}
+	err := cc0_error.Of(cc0_error.ShadowedIdentifier).WithMessage(
+		"The declaration of '%s' hides a previous declaration.", ident.Name)
+	if previous.Line > 0 {
+		err.WithNote(r.previousDeclarationOf(previous))
+	}
+	r.warnOn(cc0_error.WarnShadow, ident, err)
+}
+
+func (r *resolver) previousDeclarationOf(previous *instruction.Symbol) *Error {
+	return r.noteOnTheNameOf(previous, cc0_error.Note("Previous declaration of '%s' was here.", previous.Name))
+}
@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/token"
+)
+
+// Noted wherever one of the limits of the structs is hit, which are listed in README.txt as well.
+func noteOnTheLimitsOfStructs() *Error {
+	return cc0_error.Note("A struct can only have members of the basic types and of other structs, cannot be the " +
+		"element of an array, and cannot be returned by a function.")
+}
+
+func (a *analyzer) parseStructDeclaration() (*ast.StructDecl, *Error) {
+	// <struct-declaration> ::= 'struct' <identifier> '{' <member-declaration>{<member-declaration>} '}' ';'
+
+	structToken, err := a.expect(token.Struct, cc0_error.Of(cc0_error.InvalidDeclaration))
+	if err != nil {
+		return nil, err
+	}
+	nameToken, err := a.expect(token.Identifier, cc0_error.Of(cc0_error.InvalidDeclaration))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.expect(token.LeftBracket, cc0_error.Of(cc0_error.InvalidDeclaration)); err != nil {
+		return nil, err
+	}
+
+	decl := &ast.StructDecl{Struct: ast.PositionOf(structToken), Name: identOf(nameToken)}
+	hasInvalidMembers := false
+	for !a.diagnostics.ShouldStop() {
+		next, err := a.peekNextToken()
+		if err != nil {
+			return nil, cc0_error.Of(cc0_error.InvalidDeclaration).On(a.currentLine, a.currentColumn).WithMessage(
+				"Expected a '}' to close the body of the struct.")
+		}
+		if next.Kind == token.RightBracket {
+			_, _ = a.getNextToken()
+			decl.Rbrace = ast.EndOf(next)
+			break
+		}
+		// The struct is still declared with the other members, so that its uses are not reported as well
+		fields, fieldErr := a.parseMemberDeclaration()
+		decl.Fields = append(decl.Fields, fields...)
+		if fieldErr != nil {
+			a.recoverFrom(fieldErr)
+			hasInvalidMembers = true
+		}
+	}
+	if _, err := a.expect(token.Semicolon, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+		"Expected a ';' after the declaration of the struct.")); err != nil {
+		return nil, err
+	}
+	if len(decl.Fields) == 0 && !hasInvalidMembers {
+		a.report(cc0_error.Of(cc0_error.InvalidDeclaration).On(nameToken.Line, nameToken.Column).
+			Until(nameToken.Line, nameToken.EndColumn).WithMessage("The struct '%s' has no members.", decl.Name.Name))
+	}
+	return decl, nil
+}
+
+// Returns the members declared before the error as well.
+func (a *analyzer) parseMemberDeclaration() (fields []*ast.Field, err *Error) {
+	// <member-declaration> ::= <member-type-specifier> <identifier>{',' <identifier>} ';'
+	// <member-type-specifier> ::= 'int' | 'char' | 'double' | 'struct' <identifier>
+
+	next, readErr := a.peekNextToken()
+	if readErr != nil {
+		return nil, cc0_error.Of(cc0_error.InvalidDeclaration).On(a.currentLine, a.currentColumn)
+	}
+	if !next.IsATypeSpecifier() && next.Kind != token.Struct {
+		return nil, cc0_error.Of(cc0_error.InvalidDeclaration).On(next.Line, next.Column).WithMessage(
+			"Expected the type of a member.")
+	}
+	typeSpec, err := a.parseTypeSpecifier()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		name, err := a.expect(token.Identifier, cc0_error.Of(cc0_error.InvalidDeclaration).WithMessage(
+			"Expected the name of a member."))
+		if err != nil {
+			return fields, err
+		}
+		fields = append(fields, &ast.Field{Type: typeSpec, Name: identOf(name)})
+		next, readErr := a.getNextToken()
+		if readErr != nil {
+			return fields, cc0_error.Of(cc0_error.InvalidDeclaration).On(a.currentLine, a.currentColumn)
+		}
+		switch next.Kind {
+		case token.Comma:
+			continue
+		case token.Semicolon:
+			return fields, nil
+		case token.LeftSquareBracket:
+			return fields, cc0_error.Of(cc0_error.InvalidDeclaration).On(next.Line, next.Column).WithMessage(
+				"A member cannot be an array.").WithNote(noteOnTheLimitsOfStructs())
+		}
+		return fields, cc0_error.Of(cc0_error.InvalidDeclaration).On(next.Line, next.Column).WithMessage(
+			"Expected a ';' after the declaration of the member.")
+	}
+}
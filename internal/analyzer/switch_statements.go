@@ -0,0 +1,142 @@
+package analyzer
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/token"
+)
+
+func (a *analyzer) parseSwitchStatement() (ast.Stmt, *Error) {
+	// <switch-statement> ::= 'switch' '(' <expression> ')' '{' {<labeled-statement>} '}'
+
+	switchToken, err := a.expect(token.Switch, cc0_error.Of(cc0_error.InvalidStatement))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.expect(token.LeftParenthesis, cc0_error.Of(cc0_error.InvalidStatement)); err != nil {
+		return nil, err
+	}
+	tag, err := a.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.expect(token.RightParenthesis, cc0_error.Of(cc0_error.InvalidStatement)); err != nil {
+		return nil, err
+	}
+	if _, err := a.expect(token.LeftBracket, cc0_error.Of(cc0_error.InvalidStatement).WithMessage(
+		"Expected a '{' to start the body of the switch.")); err != nil {
+		return nil, err
+	}
+	stmt := &ast.SwitchStmt{Switch: ast.PositionOf(switchToken), Tag: tag}
+	if stmt.Clauses, stmt.Rbrace, err = a.parseLabeledStatements(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// Reads the body of a switch until the closing '}', and returns the clauses along with where the '}' ends.
+func (a *analyzer) parseLabeledStatements() (clauses []*ast.CaseClause, rbrace ast.Position, err *Error) {
+	// <labeled-statement> ::= 'case' <case-label> ':' <statement> | 'default' ':' <statement>
+
+	// The clause which the statements are added to. The statements after a rejected label are added to a clause which
+	// is dropped, so that they are not reported as being outside of any clause.
+	var clause *ast.CaseClause
+	for !a.diagnostics.ShouldStop() {
+		next, readErr := a.peekNextToken()
+		if readErr != nil {
+			return nil, rbrace, cc0_error.Of(cc0_error.InvalidStatement).On(a.currentLine, a.currentColumn).
+				WithMessage("Expected a '}' to close the body of the switch.")
+		}
+		switch next.Kind {
+		case token.RightBracket:
+			_, _ = a.getNextToken()
+			return clauses, ast.EndOf(next), nil
+		case token.Case:
+			_, _ = a.getNextToken()
+			value, colon, err := a.parseCaseLabel()
+			if err != nil {
+				clause = &ast.CaseClause{}
+				a.report(err)
+				a.skipTheRestOfTheLabel()
+				continue
+			}
+			clause = &ast.CaseClause{Case: ast.PositionOf(next), Value: value, Colon: colon}
+			clauses = append(clauses, clause)
+		case token.Default:
+			_, _ = a.getNextToken()
+			colon, err := a.expect(token.Colon, cc0_error.Of(cc0_error.IllegalCaseLabel).WithMessage(
+				"Expected a ':' after the label."))
+			if err != nil {
+				clause = &ast.CaseClause{}
+				a.report(err)
+				a.skipTheRestOfTheLabel()
+				continue
+			}
+			clause = &ast.CaseClause{Case: ast.PositionOf(next), IsDefault: true, Colon: ast.PositionOf(colon)}
+			clauses = append(clauses, clause)
+		default:
+			if clause == nil {
+				a.recoverFrom(cc0_error.Of(cc0_error.IllegalCaseLabel).On(next.Line, next.Column).WithMessage(
+					"Expected a 'case' or 'default' label."))
+				continue
+			}
+			// The statements after a label fall through to the next one
+			start := a.getCurrentPos()
+			if stmt, err := a.parseStatement(); err != nil {
+				a.recoverFrom(err)
+				clause.Body = append(clause.Body, a.badStatementFrom(start))
+			} else {
+				clause.Body = append(clause.Body, stmt)
+			}
+		}
+	}
+	return clauses, rbrace, nil
+}
+
+// Skips what is left of a rejected label up to its ':'. A label without a ':' is left where the statement or the
+// label after it starts.
+func (a *analyzer) skipTheRestOfTheLabel() {
+	for {
+		pos := a.getCurrentPos()
+		next, err := a.getNextToken()
+		if err != nil {
+			return
+		}
+		switch next.Kind {
+		case token.Colon:
+			return
+		case token.Semicolon, token.LeftBracket, token.RightBracket, token.Case, token.Default:
+			a.resetHeadTo(pos)
+			return
+		}
+	}
+}
+
+// Reads the value of a label after 'case', and returns it along with where the ':' after it is.
+func (a *analyzer) parseCaseLabel() (ast.Expr, ast.Position, *Error) {
+	// <case-label> ::= [<unary-operator>](<integer-literal>|<char-literal>)
+	next, err := a.getNextToken()
+	if err != nil {
+		return nil, ast.Position{}, cc0_error.Of(cc0_error.IllegalCaseLabel).On(a.currentLine, a.currentColumn)
+	}
+	var operator *Token
+	if next.IsAnUnaryOperator() {
+		operator = next
+		if next, err = a.getNextToken(); err != nil {
+			return nil, ast.Position{}, cc0_error.Of(cc0_error.IllegalCaseLabel).On(a.currentLine, a.currentColumn)
+		}
+	}
+	if next.Kind != token.IntegerLiteral && next.Kind != token.CharLiteral {
+		return nil, ast.Position{}, cc0_error.Of(cc0_error.IllegalCaseLabel).On(next.Line, next.Column)
+	}
+	value := literalOf(next)
+	if operator != nil {
+		value = &ast.UnaryExpr{OpPos: ast.PositionOf(operator), Op: operator.Kind, X: value}
+	}
+	colon, colonErr := a.expect(token.Colon, cc0_error.Of(cc0_error.IllegalCaseLabel).WithMessage(
+		"Expected a ':' after the label."))
+	if colonErr != nil {
+		return nil, ast.Position{}, colonErr
+	}
+	return value, ast.PositionOf(colon), nil
+}
@@ -9,26 +9,30 @@ import (
 type Parser = parser.Parser
 type Token = token.Token
 
-var currentLine = 0
-var currentColumn = 0
-
 // The only error this function will throw is NoMoreTokens so it's safe to check `err != nil` directly without
 // specifying the kind of error.
-func getNextToken() (res *Token, err error) {
-	if !globalParser.HasNextToken() {
-		res, err = nil, cc0_error.Of(cc0_error.NoMoreTokens).On(currentLine, currentColumn)
+func (a *analyzer) getNextToken() (res *Token, err error) {
+	if !a.globalParser.HasNextToken() {
+		res, err = nil, cc0_error.Of(cc0_error.NoMoreTokens).On(a.currentLine, a.currentColumn)
 		return
 	}
-	res, err = globalParser.NextToken(), nil
-	currentLine, currentColumn = res.Line, res.Column
+	res, err = a.globalParser.NextToken(), nil
+	a.currentLine, a.currentColumn = res.Line, res.Column
 	return
 }
 
-func getCurrentPos() int {
-	return globalParser.CurrentHead()
+func (a *analyzer) getCurrentPos() int {
+	return a.globalParser.CurrentHead()
+}
+
+func (a *analyzer) resetHeadTo(pos int) {
+	thatToken := a.globalParser.ResetHeadTo(pos)
+	a.currentColumn, a.currentLine = thatToken.Column, thatToken.Line
 }
 
-func resetHeadTo(pos int) {
-	thatToken := globalParser.ResetHeadTo(pos)
-	currentColumn, currentLine = thatToken.Column, thatToken.Line
+func (a *analyzer) peekNextToken() (res *Token, err error) {
+	pos := a.getCurrentPos()
+	res, err = a.getNextToken()
+	a.resetHeadTo(pos)
+	return
 }
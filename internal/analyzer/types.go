@@ -0,0 +1,434 @@
+package analyzer
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/token"
+	"fmt"
+	"strings"
+)
+
+func typeOf(sb *instruction.Symbol) ast.Type {
+	return ast.Type{Kind: sb.Kind, Struct: sb.Struct}
+}
+
+// The type both of the operands are converted to, which is the larger one of void < char < int < double.
+func largerTypeOf(lhs, rhs ast.Type) ast.Type {
+	if lhs.Kind > rhs.Kind {
+		return lhs
+	}
+	return rhs
+}
+
+// Converts the value of `x` to `t` implicitly, by wrapping it in a conversion node when the types differ.
+func (c *checker) convert(x ast.Expr, t ast.Type) ast.Expr {
+	from := x.Type()
+	if !from.IsValid() || !t.IsValid() || from == t {
+		return x
+	}
+	if !c.checkConversion(x, t) {
+		return x
+	}
+	if t.Kind < from.Kind {
+		c.warnAboutNarrowing(x, t)
+	} else {
+		c.warnOn(cc0_error.WarnImplicitConversion, x, cc0_error.Of(cc0_error.ImplicitConversion).WithMessage(
+			"The %s value is converted to %s implicitly.", from, t).
+			WithNote(cc0_error.Hint("Write '(%s)' before it to convert it explicitly.", t)))
+	}
+	return &ast.ConversionExpr{Typed: ast.Typed{T: t}, X: x}
+}
+
+// A double converted to an int or a char, or an int to a char, may lose its value. A constant which is kept as it is,
+// e.g. `65` converted to a char, is not warned about.
+func (c *checker) warnAboutNarrowing(x ast.Expr, t ast.Type) {
+	err := cc0_error.Of(cc0_error.NarrowingConversion).WithMessage(
+		"The %s value is converted to %s implicitly, which may change it.", x.Type(), t)
+	if value, ok := evaluate(x); ok {
+		converted := value.convertTo(t.Kind)
+		if converted.convertTo(value.kind).value() == value.value() {
+			return
+		}
+		err.WithNote(cc0_error.Note("The value %v becomes %v.", value.value(), converted.value()))
+	}
+	c.warnOn(cc0_error.WarnConversion, x, err.WithNote(cc0_error.Hint(
+		"Write '(%s)' before it if the conversion is intended.", t)))
+}
+
+// Whether the value of `x` can be converted to `t`, either implicitly or by a cast.
+func (c *checker) checkConversion(x ast.Expr, t ast.Type) bool {
+	// The void values have been rejected where they are used
+	if t.Kind == token.Void {
+		c.reportOn(x, cc0_error.Of(cc0_error.IllegalExpression).WithMessage("A value cannot be converted to void."))
+		return false
+	}
+	return true
+}
+
+// Fills the type of the expression and returns it. A struct is allowed here, which is only valid where a struct is
+// expected.
+func (c *checker) checkExpression(x ast.Expr) ast.Type {
+	var t ast.Type
+	switch e := x.(type) {
+	case *ast.IntegerLiteral:
+		t = ast.Int
+	case *ast.DoubleLiteral:
+		t = ast.Double
+	case *ast.CharLiteral:
+		t = ast.Char
+	case *ast.StringLiteral:
+		c.reportOn(e, cc0_error.Of(cc0_error.IllegalExpression).WithMessage("A string can only be printed."))
+	case *ast.ParenExpr:
+		t = c.checkExpression(e.X)
+	case *ast.Ident:
+		t = c.checkIdentifier(e)
+	case *ast.UnaryExpr:
+		t = c.checkIntegerOperand(e.Op, e.X, c.checkValue(e.X))
+	case *ast.BinaryExpr:
+		t = c.checkArithmeticExpression(e)
+	case *ast.CastExpr:
+		if from := c.checkValue(e.X); from.IsValid() && (from == e.To.Type || c.checkConversion(e.X, e.To.Type)) {
+			t = e.To.Type
+		}
+	case *ast.ConversionExpr:
+		t = e.Type()
+	case *ast.AssignExpr:
+		t = c.checkAssignment(e)
+	case *ast.CallExpr:
+		t = c.checkFunctionCall(e)
+	case *ast.IndexExpr:
+		t = c.checkSubscripts(e)
+	case *ast.MemberExpr:
+		t = c.checkMemberAccess(e)
+	}
+	x.SetType(t)
+	return t
+}
+
+// Checks an expression whose value is used, which cannot be a struct or the result of a void function.
+func (c *checker) checkValue(x ast.Expr) ast.Type {
+	t := c.checkExpression(x)
+	switch t.Kind {
+	case token.Struct:
+		c.reportOn(x, cc0_error.Of(cc0_error.IllegalMemberAccess).WithMessage(
+			"The struct '%s' can only be assigned, used to initialize a variable or passed to a function.",
+			nameOf(x)))
+	case token.Void:
+		err := cc0_error.Of(cc0_error.VoidValue)
+		if call, ok := ast.Unparen(x).(*ast.CallExpr); ok {
+			err.WithMessage("The function '%s' returns void, whose result cannot be used as a value.",
+				call.Fun.Name).WithNote(c.declarationOf(call.Fun.Symbol))
+		}
+		c.reportOn(x, err)
+	default:
+		return t
+	}
+	x.SetType(ast.Type{})
+	return ast.Type{}
+}
+
+func (c *checker) checkArithmeticExpression(e *ast.BinaryExpr) ast.Type {
+	lhs, rhs := c.checkValue(e.X), c.checkValue(e.Y)
+	if !lhs.IsValid() || !rhs.IsValid() {
+		return ast.Type{}
+	}
+	if _, ok := integerOperators[e.Op]; ok {
+		lhs, rhs = c.checkIntegerOperand(e.Op, e.X, lhs), c.checkIntegerOperand(e.Op, e.Y, rhs)
+		if !lhs.IsValid() || !rhs.IsValid() {
+			return ast.Type{}
+		}
+	}
+	t := largerTypeOf(lhs, rhs)
+	e.X, e.Y = c.convert(e.X, t), c.convert(e.Y, t)
+	return t
+}
+
+// The operators which only apply to the ints and the chars, along with how they are written.
+var integerOperators = map[int]string{
+	token.ModuloSign: "%",
+	token.BitwiseAnd: "&",
+	token.BitwiseOr:  "|",
+	token.BitwiseXor: "^",
+	token.BitwiseNot: "~",
+	token.LeftShift:  "<<",
+	token.RightShift: ">>",
+}
+
+// Returns the type `t` of the operand `x` of the operator, or the invalid type if the operator applies only to the
+// ints and the chars and `x` is a double.
+func (c *checker) checkIntegerOperand(operator int, x ast.Expr, t ast.Type) ast.Type {
+	spelling, ok := integerOperators[operator]
+	if !ok || !t.IsValid() || t.Kind == token.Int || t.Kind == token.Char {
+		return t
+	}
+	c.reportOn(x, cc0_error.Of(cc0_error.IllegalExpression).WithMessage(
+		"The operator '%s' can only be applied to ints and chars, but the operand is %s.", spelling, t).
+		WithNote(cc0_error.Hint("Write '(int)' before it to convert it explicitly.")))
+	return ast.Type{}
+}
+
+// Checks an expression which is tested, whose type is int once checked, and returns it with its known parts folded.
+func (c *checker) checkCondition(x ast.Expr) ast.Expr {
+	c.checkLogicalCondition(x)
+	return fold(x)
+}
+
+func (c *checker) checkLogicalCondition(x ast.Expr) {
+	switch e := x.(type) {
+	case *ast.ParenExpr:
+		c.checkLogicalCondition(e.X)
+	case *ast.UnaryExpr:
+		if e.Op != token.LogicalNot {
+			c.checkValue(e)
+			return
+		}
+		c.checkLogicalCondition(e.X)
+	case *ast.BinaryExpr:
+		switch {
+		case e.Op == token.LogicalAnd || e.Op == token.LogicalOr:
+			c.checkLogicalCondition(e.X)
+			c.checkLogicalCondition(e.Y)
+		case isARelationalOperator(e.Op):
+			c.checkArithmeticExpression(e)
+		default:
+			c.checkValue(e)
+			return
+		}
+	default:
+		c.checkValue(e)
+		return
+	}
+	x.SetType(ast.Int)
+}
+
+func isARelationalOperator(kind int) bool {
+	t := &Token{Kind: kind}
+	return t.IsARelationalOperator()
+}
+
+func (c *checker) checkIdentifier(ident *ast.Ident) ast.Type {
+	sb := ident.Symbol
+	switch {
+	case sb == nil:
+		return ast.Type{}
+	case sb.IsCallable:
+		c.report(cc0_error.Of(cc0_error.IncompleteFunctionCall).On(ident.End().Line, ident.End().Column))
+		return ast.Type{}
+	case sb.IsAnArray():
+		c.reportOn(ident, cc0_error.Of(cc0_error.IllegalArrayAccess).WithMessage(
+			"The array '%s' can only be used with subscripts.", sb.Name))
+		return ast.Type{}
+	}
+	return typeOf(sb)
+}
+
+func (c *checker) checkSubscripts(e *ast.IndexExpr) ast.Type {
+	for _, index := range e.Indices {
+		if t := c.checkValue(index); t.IsValid() && t.Kind != token.Int && t.Kind != token.Char {
+			c.reportOn(index, cc0_error.Of(cc0_error.IllegalArrayAccess).WithMessage(
+				"The subscript of an array must be an int or a char."))
+		}
+	}
+	sb := e.Array.Symbol
+	switch {
+	case sb == nil:
+		return ast.Type{}
+	case !sb.IsAnArray():
+		c.reportOn(e, cc0_error.Of(cc0_error.IllegalArrayAccess).WithMessage("'%s' is not an array.", sb.Name))
+		return ast.Type{}
+	case len(e.Indices) < len(sb.Dimensions):
+		c.reportOn(e.Array, cc0_error.Of(cc0_error.IllegalArrayAccess).WithMessage(
+			"The array '%s' has %d dimension(s) but only %d subscript(s) are given.",
+			sb.Name, len(sb.Dimensions), len(e.Indices)))
+		return ast.Type{}
+	case len(e.Indices) > len(sb.Dimensions):
+		c.reportOn(e.Indices[len(sb.Dimensions)], cc0_error.Of(cc0_error.IllegalArrayAccess).WithMessage(
+			"The array '%s' has only %d dimension(s).", sb.Name, len(sb.Dimensions)))
+		return ast.Type{}
+	}
+	return typeOf(sb)
+}
+
+// A struct is kept in the consecutive slots of its variable, so a member is found by its offset in the struct.
+func (c *checker) checkMemberAccess(e *ast.MemberExpr) ast.Type {
+	t := c.checkExpression(e.X)
+	if !t.IsValid() {
+		return t
+	}
+	if t.Kind != token.Struct {
+		c.reportOn(e.X, cc0_error.Of(cc0_error.IllegalMemberAccess).WithMessage("'%s' is not a struct.", nameOf(e.X)))
+		return ast.Type{}
+	}
+	name := e.Member.Name
+	if e.Field = t.Struct.GetMemberNamed(name); e.Field == nil {
+		err := cc0_error.Of(cc0_error.IllegalMemberAccess).WithMessage(
+			"'struct %s' has no member named '%s'.", t.Struct.Name, name)
+		if similarName := t.Struct.GetSimilarMemberName(name); similarName != "" {
+			err.WithNote(cc0_error.Hint("Did you mean '%s'?", similarName))
+		}
+		c.reportOn(e.Member, err)
+		return ast.Type{}
+	}
+	return typeOf(e.Field)
+}
+
+// Checks the left hand side of an assignment or the target of a scan, which should be a variable, an element of an
+// array or a member of a struct, and returns its type.
+func (c *checker) checkAssignable(x ast.Expr) ast.Type {
+	if _, ok := x.(*ast.CallExpr); ok {
+		c.checkExpression(x)
+		c.reportOn(x, cc0_error.Of(cc0_error.IllegalExpression).WithMessage(
+			"Only a variable, an element of an array or a member of a struct can be assigned."))
+		return ast.Type{}
+	}
+	if root := rootOf(x); root != nil && root.Symbol != nil && root.Symbol.IsConstant && !root.Symbol.IsCallable {
+		c.reportOn(root, cc0_error.Of(cc0_error.AssignmentToConstant).WithMessage(
+			"Cannot assign a new value to the constant: %s", root.Name))
+		return ast.Type{}
+	}
+	return c.checkExpression(x)
+}
+
+func (c *checker) checkAssignment(e *ast.AssignExpr) ast.Type {
+	t := c.checkAssignable(e.Lhs)
+	if t.Kind == token.Struct {
+		e.Rhs = c.checkStructOperand(e.Rhs, t.Struct)
+		return t
+	}
+	c.checkValue(e.Rhs)
+	e.Rhs = c.convert(e.Rhs, t)
+	return t
+}
+
+// Checks a struct which is assigned, used to initialize a variable or passed as a whole, which should be of the type
+// `expected`.
+func (c *checker) checkStructOperand(x ast.Expr, expected *instruction.StructType) ast.Expr {
+	root := rootOf(x)
+	if _, ok := x.(*ast.IndexExpr); ok || root == nil || (root.Symbol != nil &&
+		(root.Symbol.IsCallable || root.Symbol.IsAnArray())) {
+		c.checkExpression(x)
+		c.reportOn(x, cc0_error.Of(cc0_error.IncompatibleStructs).WithMessage(
+			"Expected a 'struct %s' here.", expected.Name))
+		return x
+	}
+	t := c.checkExpression(x)
+	if t.IsValid() && (t.Kind != token.Struct || t.Struct != expected) {
+		typeName := "a scalar"
+		if t.Kind == token.Struct {
+			typeName = "'struct " + t.Struct.Name + "'"
+		}
+		c.reportOn(x, cc0_error.Of(cc0_error.IncompatibleStructs).WithMessage(
+			"Expected a 'struct %s' but '%s' is %s.", expected.Name, nameOf(x), typeName))
+	}
+	return x
+}
+
+func (c *checker) checkFunctionCall(e *ast.CallExpr) ast.Type {
+	sb := e.Fun.Symbol
+	if sb == nil || !sb.IsCallable {
+		for _, arg := range e.Args {
+			c.checkValue(arg)
+		}
+		if sb != nil {
+			c.reportOn(e.Fun, cc0_error.Of(cc0_error.IllegalExpression).WithMessage("'%s' is not a function.", sb.Name))
+		}
+		return ast.Type{}
+	}
+
+	parameters := *sb.FnInfo.Parameters
+	for index := range e.Args {
+		if index >= len(parameters) {
+			c.checkValue(e.Args[index])
+			continue
+		}
+		e.Args[index] = c.checkArgument(e, index, sb.FnInfo.RelatedSymbolTable.GetSymbolNamed(parameters[index]))
+	}
+	if len(e.Args) != len(parameters) {
+		c.reportOn(e, cc0_error.Of(cc0_error.ArgumentCountMismatch).WithMessage(
+			"The function '%s' expects %d argument(s) but is given %d.", sb.Name, len(parameters), len(e.Args)).
+			WithNote(c.declarationOf(sb)))
+	}
+	return ast.Type{Kind: sb.Kind}
+}
+
+// Checks the argument passed as the parameter, which is converted to the type of the parameter implicitly. An
+// argument which cannot be is reported with the parameter it is passed as.
+func (c *checker) checkArgument(e *ast.CallExpr, index int, parameter *instruction.Symbol) ast.Expr {
+	arg := e.Args[index]
+	var given string
+	if ident, ok := ast.Unparen(arg).(*ast.Ident); ok && ident.Symbol != nil && ident.Symbol.IsAnArray() {
+		given = fmt.Sprintf("the array '%s'", ident.Name)
+	} else {
+		switch t := c.checkExpression(arg); {
+		case !t.IsValid():
+			return arg
+		case parameter.Kind == token.Struct && t.Kind == token.Struct && t.Struct == parameter.Struct:
+			return arg
+		case t.Kind == token.Void:
+			given = "a void value"
+			if call, ok := ast.Unparen(arg).(*ast.CallExpr); ok {
+				given = fmt.Sprintf("the result of the void function '%s'", call.Fun.Name)
+			}
+		case t.Kind == token.Struct:
+			given = fmt.Sprintf("the struct '%s' of type '%s'", nameOf(arg), t)
+		case parameter.Kind == token.Struct:
+			given = fmt.Sprintf("a value of type '%s'", t)
+		default:
+			return c.convert(arg, typeOf(parameter))
+		}
+	}
+	c.reportOn(arg, cc0_error.Of(cc0_error.IllegalArgument).WithMessage(
+		"Cannot pass %s as the argument %d of '%s', whose parameter '%s' is of type '%s'.", given, index+1,
+		e.Fun.Name, parameter.Name, declaredTypeOf(parameter)).WithNote(c.declarationOf(e.Fun.Symbol)))
+	return arg
+}
+
+// A note on where the function is declared, which shows its signature, e.g. `int max(int a, const int b)`.
+func (c *checker) declarationOf(fn *instruction.Symbol) *Error {
+	params := []string{}
+	for _, name := range *fn.FnInfo.Parameters {
+		param := fn.FnInfo.RelatedSymbolTable.GetSymbolNamed(name)
+		params = append(params, declaredTypeOf(param)+" "+name)
+	}
+	note := cc0_error.Note("The function is declared as '%s %s(%s)'.", ast.Type{Kind: fn.Kind}, fn.Name,
+		strings.Join(params, ", "))
+	if fn.Line == 0 {
+		// The function is redeclared, whose declaration has been reported
+		return note
+	}
+	return c.noteOnTheNameOf(fn, note)
+}
+
+func declaredTypeOf(sb *instruction.Symbol) string {
+	if sb.IsConstant {
+		return "const " + typeOf(sb).String()
+	}
+	return typeOf(sb).String()
+}
+
+// The variable that the expression is a part of, e.g. `p` of `p.from.x`, or nil if it is not a part of a variable.
+func rootOf(x ast.Expr) *ast.Ident {
+	switch e := x.(type) {
+	case *ast.Ident:
+		return e
+	case *ast.IndexExpr:
+		return e.Array
+	case *ast.MemberExpr:
+		return rootOf(e.X)
+	}
+	return nil
+}
+
+// How the expression is written if it is a part of a variable, e.g. "p.from.x".
+func nameOf(x ast.Expr) string {
+	switch e := ast.Unparen(x).(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.IndexExpr:
+		return e.Array.Name + "[...]"
+	case *ast.MemberExpr:
+		return nameOf(e.X) + "." + e.Member.Name
+	}
+	return "the expression"
+}
@@ -4,33 +4,31 @@ import (
 	"bytes"
 	"c0_compiler/internal/cc0_error"
 	"c0_compiler/internal/instruction"
-	"c0_compiler/internal/token"
 	"encoding/binary"
 	"fmt"
 	"sort"
 )
 
-var sortedFunctions = &[]instruction.Symbol{}
-var lines = &[]string{}
-var addressOffset int // for constants
+// The state of assembling a single program.
+type assembler struct {
+	sortedFunctions *[]instruction.Symbol
+	lines           *[]string
+	addressOffset   int // for constants
+}
 
-func appendLine(format string, params ...interface{}) {
-	*lines = append(*lines, fmt.Sprintf(format, params...))
+func (a *assembler) appendLine(format string, params ...interface{}) {
+	*a.lines = append(*a.lines, fmt.Sprintf(format, params...))
 }
 
-func appendEmptyLine() {
-	*lines = append(*lines, "\n")
+func (a *assembler) appendEmptyLine() {
+	*a.lines = append(*a.lines, "\n")
 }
 
-func printLine(line instruction.Line) {
+func (a *assembler) printLine(line instruction.Line) {
 	if line.I.Code == instruction.Loadc && (*line.Operands)[0] <= 0 {
-		(*line.Operands)[0] = addressOffset - (*line.Operands)[0]
-	}
-	str := line.I.Representation
-	for _, operand := range *line.Operands {
-		str += fmt.Sprintf(" %d", operand)
+		(*line.Operands)[0] = a.addressOffset - (*line.Operands)[0]
 	}
-	appendLine("%s\n", str)
+	a.appendLine("%s\n", line.String())
 }
 
 func float64ToByte(f float64) []byte {
@@ -42,14 +40,14 @@ func float64ToByte(f float64) []byte {
 	return buf.Bytes()
 }
 
-func assembleConstants(st *instruction.SymbolTable) {
-	appendLine(".constants:\n")
-	for index, sb := range *sortedFunctions {
-		appendLine("%d S \"%s\"\n", index, sb.Name)
+func (a *assembler) assembleConstants(st *instruction.SymbolTable) {
+	a.appendLine(".constants:\n")
+	for index, sb := range *a.sortedFunctions {
+		a.appendLine("%d S %s\n", index, instruction.QuoteString(sb.Name))
 	}
-	addressOffset = len(*sortedFunctions)
+	a.addressOffset = len(*a.sortedFunctions)
 	for _, c := range *st.Constants {
-		address := addressOffset - c.Address
+		address := a.addressOffset - c.Address
 		switch c.Kind {
 		case instruction.ConstantKindInt:
 			// not used
@@ -58,27 +56,22 @@ func assembleConstants(st *instruction.SymbolTable) {
 			for _, b := range float64ToByte(c.Value.(float64)) {
 				str += fmt.Sprintf("%02x", b)
 			}
-			appendLine("%d D %s\n", address, str)
+			a.appendLine("%d D %s\n", address, str)
 		case instruction.ConstantKindString:
-			appendLine("%d S \"%s\"\n", address, c.Value)
+			a.appendLine("%d S %s\n", address, instruction.QuoteString(c.Value.(string)))
 		}
 	}
-	appendEmptyLine()
+	a.appendEmptyLine()
 }
 
-func assembleFunctions() {
-	appendLine(".functions:\n")
-	for index, sb := range *sortedFunctions {
+func (a *assembler) assembleFunctions() {
+	a.appendLine(".functions:\n")
+	for index, sb := range *a.sortedFunctions {
 		requiredSize := 0
 		for _, parameterName := range *sb.FnInfo.Parameters {
-			switch sb.FnInfo.RelatedSymbolTable.GetSymbolNamed(parameterName).Kind {
-			case token.Double:
-				requiredSize += 2
-			default:
-				requiredSize += 1
-			}
+			requiredSize += sb.FnInfo.RelatedSymbolTable.GetSymbolNamed(parameterName).Size()
 		}
-		appendLine("%d %d %d 1\t# %s\n", index, index, requiredSize, sb.Name)
+		a.appendLine("%d %d %d 1\t# %s\n", index, index, requiredSize, sb.Name)
 	}
 }
 
@@ -109,42 +102,44 @@ func (by By) Sort(functions []instruction.Symbol) {
 	sort.Sort(ps)
 }
 
-func sortFunctions(table *instruction.SymbolTable) {
+func (a *assembler) sortFunctions(table *instruction.SymbolTable) {
 	for _, sb := range table.Symbols {
 		if !sb.IsCallable {
 			continue
 		}
-		*sortedFunctions = append(*sortedFunctions, *sb)
+		*a.sortedFunctions = append(*a.sortedFunctions, *sb)
 	}
-	By(func(p1, p2 *instruction.Symbol) bool { return p1.Address < p2.Address }).Sort(*sortedFunctions)
+	By(func(p1, p2 *instruction.Symbol) bool { return p1.Address < p2.Address }).Sort(*a.sortedFunctions)
 }
 
-func Run(globalSymbolTable *instruction.SymbolTable) *[]string {
+func Run(globalSymbolTable *instruction.SymbolTable, diagnostics *cc0_error.Diagnostics) *[]string {
+	a := &assembler{sortedFunctions: &[]instruction.Symbol{}, lines: &[]string{}}
 	count := 0
 
 	// Check if `main` function exists first
 	if _, ok := globalSymbolTable.Symbols["main"]; !ok {
-		cc0_error.Of(cc0_error.NoMain).Die(cc0_error.Assembler)
+		diagnostics.Report(cc0_error.Assembler, cc0_error.Of(cc0_error.NoMain))
+		return a.lines
 	}
 
-	sortFunctions(globalSymbolTable)
-	assembleConstants(globalSymbolTable)
+	a.sortFunctions(globalSymbolTable)
+	a.assembleConstants(globalSymbolTable)
 
-	appendLine(".start:\n")
+	a.appendLine(".start:\n")
 	for _, i := range *globalSymbolTable.RelatedFunction.GetLines() {
-		printLine(i)
+		a.printLine(i)
 	}
-	appendEmptyLine()
+	a.appendEmptyLine()
 
-	assembleFunctions()
+	a.assembleFunctions()
 
-	for name, sb := range *sortedFunctions {
-		appendLine("\n.F%d:\t# %s\n", count, name)
+	for _, sb := range *a.sortedFunctions {
+		a.appendLine("\n.F%d:\t# %s\n", count, sb.Name)
 		for _, i := range *sb.FnInfo.GetLines() {
-			printLine(i)
+			a.printLine(i)
 		}
 		count++
 	}
 
-	return lines
+	return a.lines
 }
@@ -0,0 +1,517 @@
+// Package ast declares the syntax tree of C0 programs. The tree is built by the parser in the analyzer, annotated by
+// the name resolution and the type checking passes, and then walked by the code generator.
+package ast
+
+import (
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/token"
+)
+
+// Lines and columns count from 1.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func PositionOf(t *token.Token) Position {
+	return Position{t.Line, t.Column}
+}
+
+// Right after where the token ends.
+func EndOf(t *token.Token) Position {
+	return Position{t.Line, t.EndColumn}
+}
+
+type Node interface {
+	Pos() Position // where the node starts
+	End() Position // right after where the node ends
+}
+
+// The type of an expression or of what is declared. The zero value is an invalid type, which is given to the
+// expressions with errors so that they aren't reported again.
+type Type struct {
+	Kind   int                     // one of token.Void, token.Char, token.Int, token.Double and token.Struct
+	Struct *instruction.StructType // the layout of the struct if `Kind` is token.Struct
+}
+
+var (
+	Void   = Type{Kind: token.Void}
+	Char   = Type{Kind: token.Char}
+	Int    = Type{Kind: token.Int}
+	Double = Type{Kind: token.Double}
+)
+
+func StructOf(structType *instruction.StructType) Type {
+	return Type{Kind: token.Struct, Struct: structType}
+}
+
+func (t Type) IsValid() bool {
+	return t.Kind != 0
+}
+
+// Whether it is one of int, char and double.
+func (t Type) IsArithmetic() bool {
+	return t.Kind == token.Int || t.Kind == token.Char || t.Kind == token.Double
+}
+
+func (t Type) String() string {
+	switch t.Kind {
+	case token.Void:
+		return "void"
+	case token.Char:
+		return "char"
+	case token.Int:
+		return "int"
+	case token.Double:
+		return "double"
+	case token.Struct:
+		return "struct " + t.Struct.Name
+	}
+	return "<invalid>"
+}
+
+// ----------------------------------------------------------------------------
+// Declarations
+
+type Decl interface {
+	Node
+	declNode()
+}
+
+type Program struct {
+	Decls []Decl // in the order of the source, the variables and the structs come before the functions
+}
+
+func (p *Program) Pos() Position {
+	if len(p.Decls) == 0 {
+		return Position{1, 1}
+	}
+	return p.Decls[0].Pos()
+}
+
+func (p *Program) End() Position {
+	if len(p.Decls) == 0 {
+		return Position{1, 1}
+	}
+	return p.Decls[len(p.Decls)-1].End()
+}
+
+// The type in a declaration, e.g. `int` or `struct Point`.
+type TypeSpec struct {
+	Keyword    Position
+	Kind       int    // the keyword, one of token.Void, token.Char, token.Int, token.Double and token.Struct
+	StructName *Ident // the name after 'struct'
+	Type       Type   // filled by the name resolution
+}
+
+// A single declarator of a variable declaration, e.g. `y = 2` of `const int x = 1, y = 2;`, which shares the
+// `TypeSpec` with the other declarators.
+type VarDecl struct {
+	IsConstant bool
+	Type       *TypeSpec
+	Name       *Ident
+	Dimensions []*IntegerLiteral // the lengths of the dimensions of an array
+	Rbrack     Position          // right after the last ']' of an array
+	Init       Expr              // or nil
+}
+
+type Field struct {
+	Type *TypeSpec
+	Name *Ident
+}
+
+type StructDecl struct {
+	Struct Position // of the keyword
+	Name   *Ident
+	Fields []*Field
+	Rbrace Position
+	Layout *instruction.StructType // filled by the name resolution
+}
+
+type Param struct {
+	IsConstant bool
+	Const      Position // of the qualifier if `IsConstant`
+	Type       *TypeSpec
+	Name       *Ident
+}
+
+type FuncDecl struct {
+	ReturnType   *TypeSpec
+	Name         *Ident
+	Params       []*Param
+	Rparen       Position
+	IsAPrototype bool
+	Semicolon    Position   // right after the ';' if `IsAPrototype`
+	Body         *BlockStmt // nil if the function is a prototype or is not complete
+}
+
+func (t *TypeSpec) Pos() Position { return t.Keyword }
+func (t *TypeSpec) End() Position {
+	if t.StructName != nil {
+		return t.StructName.End()
+	}
+	return Position{t.Keyword.Line, t.Keyword.Column + len(Type{Kind: t.Kind}.String())}
+}
+
+func (d *VarDecl) Pos() Position { return d.Name.Pos() }
+func (d *VarDecl) End() Position {
+	switch {
+	case d.Init != nil:
+		return d.Init.End()
+	case len(d.Dimensions) > 0:
+		return d.Rbrack
+	}
+	return d.Name.End()
+}
+
+func (f *Field) Pos() Position { return f.Type.Pos() }
+func (f *Field) End() Position { return f.Name.End() }
+
+func (d *StructDecl) Pos() Position { return d.Struct }
+func (d *StructDecl) End() Position { return d.Rbrace }
+
+func (p *Param) Pos() Position {
+	if p.IsConstant {
+		return p.Const
+	}
+	return p.Type.Pos()
+}
+func (p *Param) End() Position { return p.Name.End() }
+
+func (d *FuncDecl) Pos() Position { return d.ReturnType.Pos() }
+func (d *FuncDecl) End() Position {
+	switch {
+	case d.Body != nil:
+		return d.Body.End()
+	case d.IsAPrototype:
+		return d.Semicolon
+	}
+	return d.Rparen
+}
+
+func (*VarDecl) declNode()    {}
+func (*StructDecl) declNode() {}
+func (*FuncDecl) declNode()   {}
+
+// ----------------------------------------------------------------------------
+// Statements
+
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+type BlockStmt struct {
+	Lbrace Position
+	Decls  []*VarDecl
+	Stmts  []Stmt
+	Rbrace Position                 // right after the '}'
+	Scope  *instruction.SymbolTable // filled by the name resolution
+}
+
+// An assignment or a function call followed by a ';'.
+type ExprStmt struct {
+	X         Expr
+	Semicolon Position // right after the ';'
+}
+
+type EmptyStmt struct {
+	Semicolon Position
+}
+
+// Stands for a statement with syntax errors, which is dropped. The identifiers in it are kept only so that the
+// variables they name are not warned about as never used.
+type BadStmt struct {
+	From, To Position
+	Names    []*Ident
+}
+
+type IfStmt struct {
+	If   Position
+	Cond Expr
+	Then Stmt
+	Else Stmt // or nil
+}
+
+type WhileStmt struct {
+	While Position
+	Cond  Expr
+	Body  Stmt
+}
+
+type DoWhileStmt struct {
+	Do        Position
+	Body      Stmt
+	Cond      Expr
+	Semicolon Position // right after the ';'
+}
+
+type ForStmt struct {
+	For    Position
+	Init   []Expr // assignments
+	Cond   Expr   // or nil
+	Update []Expr // assignments and function calls
+	Body   Stmt
+}
+
+type SwitchStmt struct {
+	Switch  Position
+	Tag     Expr
+	Clauses []*CaseClause
+	Rbrace  Position // right after the '}'
+}
+
+// A label with the statements after it until the next label, which fall through to the next clause.
+type CaseClause struct {
+	Case      Position // of the 'case' or 'default' keyword
+	IsDefault bool
+	Value     Expr // an integer or a char literal, which may be negated
+	Label     int  // the value of `Value`, filled by the type checking
+	Colon     Position
+	Body      []Stmt
+}
+
+type BranchStmt struct {
+	Keyword   Position
+	Kind      int      // token.Break or token.Continue
+	Semicolon Position // right after the ';'
+}
+
+type ReturnStmt struct {
+	Return    Position
+	Result    Expr     // or nil
+	Semicolon Position // right after the ';'
+}
+
+type PrintStmt struct {
+	Print     Position
+	Args      []Expr   // expressions and string literals
+	Semicolon Position // right after the ';'
+}
+
+type ScanStmt struct {
+	Scan      Position
+	Target    Expr
+	Semicolon Position // right after the ';'
+}
+
+func (s *BlockStmt) Pos() Position   { return s.Lbrace }
+func (s *BlockStmt) End() Position   { return s.Rbrace }
+func (s *ExprStmt) Pos() Position    { return s.X.Pos() }
+func (s *ExprStmt) End() Position    { return s.Semicolon }
+func (s *EmptyStmt) Pos() Position   { return s.Semicolon }
+func (s *EmptyStmt) End() Position   { return Position{s.Semicolon.Line, s.Semicolon.Column + 1} }
+func (s *BadStmt) Pos() Position     { return s.From }
+func (s *BadStmt) End() Position     { return s.To }
+func (s *IfStmt) Pos() Position      { return s.If }
+func (s *WhileStmt) Pos() Position   { return s.While }
+func (s *WhileStmt) End() Position   { return s.Body.End() }
+func (s *DoWhileStmt) Pos() Position { return s.Do }
+func (s *DoWhileStmt) End() Position { return s.Semicolon }
+func (s *ForStmt) Pos() Position     { return s.For }
+func (s *ForStmt) End() Position     { return s.Body.End() }
+func (s *SwitchStmt) Pos() Position  { return s.Switch }
+func (s *SwitchStmt) End() Position  { return s.Rbrace }
+func (s *CaseClause) Pos() Position  { return s.Case }
+func (s *BranchStmt) Pos() Position  { return s.Keyword }
+func (s *BranchStmt) End() Position  { return s.Semicolon }
+func (s *ReturnStmt) Pos() Position  { return s.Return }
+func (s *ReturnStmt) End() Position  { return s.Semicolon }
+func (s *PrintStmt) Pos() Position   { return s.Print }
+func (s *PrintStmt) End() Position   { return s.Semicolon }
+func (s *ScanStmt) Pos() Position    { return s.Scan }
+func (s *ScanStmt) End() Position    { return s.Semicolon }
+
+func (s *IfStmt) End() Position {
+	if s.Else != nil {
+		return s.Else.End()
+	}
+	return s.Then.End()
+}
+
+func (s *CaseClause) End() Position {
+	if len(s.Body) > 0 {
+		return s.Body[len(s.Body)-1].End()
+	}
+	return Position{s.Colon.Line, s.Colon.Column + 1}
+}
+
+func (*BlockStmt) stmtNode()   {}
+func (*ExprStmt) stmtNode()    {}
+func (*EmptyStmt) stmtNode()   {}
+func (*BadStmt) stmtNode()     {}
+func (*IfStmt) stmtNode()      {}
+func (*WhileStmt) stmtNode()   {}
+func (*DoWhileStmt) stmtNode() {}
+func (*ForStmt) stmtNode()     {}
+func (*SwitchStmt) stmtNode()  {}
+func (*BranchStmt) stmtNode()  {}
+func (*ReturnStmt) stmtNode()  {}
+func (*PrintStmt) stmtNode()   {}
+func (*ScanStmt) stmtNode()    {}
+
+// ----------------------------------------------------------------------------
+// Expressions
+
+type Expr interface {
+	Node
+	Type() Type
+	SetType(Type)
+}
+
+// The type of an expression, which is filled by the type checking.
+type Typed struct {
+	T Type
+}
+
+func (t *Typed) Type() Type       { return t.T }
+func (t *Typed) SetType(typ Type) { t.T = typ }
+
+// Stands for an expression with syntax errors, so that the declaration around it is still known.
+type BadExpr struct {
+	Typed
+	From, To Position
+}
+
+type Ident struct {
+	Typed
+	NamePos Position
+	Name    string
+	Symbol  *instruction.Symbol // filled by the name resolution
+}
+
+type IntegerLiteral struct {
+	Typed
+	ValuePos, ValueEnd Position
+	Value              int64
+}
+
+type DoubleLiteral struct {
+	Typed
+	ValuePos, ValueEnd Position
+	Value              float64
+}
+
+type CharLiteral struct {
+	Typed
+	ValuePos, ValueEnd Position
+	Value              int32
+}
+
+// Only allowed in the print statements.
+type StringLiteral struct {
+	Typed
+	ValuePos, ValueEnd Position
+	Value              string
+}
+
+type ParenExpr struct {
+	Typed
+	Lparen Position
+	X      Expr
+	Rparen Position // right after the ')'
+}
+
+// `Op` is one of token.PlusSign, token.MinusSign, token.BitwiseNot and token.LogicalNot.
+type UnaryExpr struct {
+	Typed
+	OpPos Position
+	Op    int
+	X     Expr
+}
+
+// `Op` is an arithmetic, a bitwise, a shift, a relational or a logical operator. The relational and the logical ones
+// only appear in the conditions.
+type BinaryExpr struct {
+	Typed
+	X     Expr
+	OpPos Position
+	Op    int
+	Y     Expr
+}
+
+// An explicit conversion, e.g. `(int)d`.
+type CastExpr struct {
+	Typed
+	Lparen Position
+	To     *TypeSpec
+	X      Expr
+}
+
+// An implicit conversion of `X` to the type of the node, which is inserted by the type checking, e.g. around the
+// int operand of `1 + 2.0`.
+type ConversionExpr struct {
+	Typed
+	X Expr
+}
+
+type AssignExpr struct {
+	Typed
+	Lhs   Expr
+	OpPos Position
+	Rhs   Expr
+}
+
+type CallExpr struct {
+	Typed
+	Fun    *Ident
+	Args   []Expr
+	Rparen Position // right after the ')'
+}
+
+// An element of an array, e.g. `m[i][j]`, with a subscript for each of the dimensions.
+type IndexExpr struct {
+	Typed
+	Array   *Ident
+	Indices []Expr
+	Rbrack  Position // right after the last ']'
+}
+
+type MemberExpr struct {
+	Typed
+	X      Expr // a struct
+	Member *Ident
+	Field  *instruction.Symbol // filled by the type checking, whose `Address` is the offset in the struct
+}
+
+func (e *BadExpr) Pos() Position        { return e.From }
+func (e *BadExpr) End() Position        { return e.To }
+func (e *Ident) Pos() Position          { return e.NamePos }
+func (e *Ident) End() Position          { return Position{e.NamePos.Line, e.NamePos.Column + len(e.Name)} }
+func (e *IntegerLiteral) Pos() Position { return e.ValuePos }
+func (e *IntegerLiteral) End() Position { return e.ValueEnd }
+func (e *DoubleLiteral) Pos() Position  { return e.ValuePos }
+func (e *DoubleLiteral) End() Position  { return e.ValueEnd }
+func (e *CharLiteral) Pos() Position    { return e.ValuePos }
+func (e *CharLiteral) End() Position    { return e.ValueEnd }
+func (e *StringLiteral) Pos() Position  { return e.ValuePos }
+func (e *StringLiteral) End() Position  { return e.ValueEnd }
+func (e *ParenExpr) Pos() Position      { return e.Lparen }
+func (e *ParenExpr) End() Position      { return e.Rparen }
+func (e *UnaryExpr) Pos() Position      { return e.OpPos }
+func (e *UnaryExpr) End() Position      { return e.X.End() }
+func (e *BinaryExpr) Pos() Position     { return e.X.Pos() }
+func (e *BinaryExpr) End() Position     { return e.Y.End() }
+func (e *CastExpr) Pos() Position       { return e.Lparen }
+func (e *CastExpr) End() Position       { return e.X.End() }
+func (e *ConversionExpr) Pos() Position { return e.X.Pos() }
+func (e *ConversionExpr) End() Position { return e.X.End() }
+func (e *AssignExpr) Pos() Position     { return e.Lhs.Pos() }
+func (e *AssignExpr) End() Position     { return e.Rhs.End() }
+func (e *CallExpr) Pos() Position       { return e.Fun.Pos() }
+func (e *CallExpr) End() Position       { return e.Rparen }
+func (e *IndexExpr) Pos() Position      { return e.Array.Pos() }
+func (e *IndexExpr) End() Position      { return e.Rbrack }
+func (e *MemberExpr) Pos() Position     { return e.X.Pos() }
+func (e *MemberExpr) End() Position     { return e.Member.End() }
+
+// Removes the parentheses around the expression.
+func Unparen(e Expr) Expr {
+	for {
+		paren, ok := e.(*ParenExpr)
+		if !ok {
+			return e
+		}
+		e = paren.X
+	}
+}
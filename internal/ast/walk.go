@@ -0,0 +1,124 @@
+package ast
+
+// Calls `f` on `node` and then, if `f` returns true, on each of the children of `node` in the order of the source.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *Program:
+		for _, decl := range n.Decls {
+			Inspect(decl, f)
+		}
+	case *TypeSpec:
+		inspectIdent(n.StructName, f)
+	case *VarDecl:
+		Inspect(n.Type, f)
+		Inspect(n.Name, f)
+		for _, length := range n.Dimensions {
+			Inspect(length, f)
+		}
+		Inspect(n.Init, f)
+	case *Field:
+		Inspect(n.Type, f)
+		Inspect(n.Name, f)
+	case *StructDecl:
+		Inspect(n.Name, f)
+		for _, field := range n.Fields {
+			Inspect(field, f)
+		}
+	case *Param:
+		Inspect(n.Type, f)
+		Inspect(n.Name, f)
+	case *FuncDecl:
+		Inspect(n.ReturnType, f)
+		Inspect(n.Name, f)
+		for _, param := range n.Params {
+			Inspect(param, f)
+		}
+		if n.Body != nil {
+			Inspect(n.Body, f)
+		}
+
+	case *BlockStmt:
+		for _, decl := range n.Decls {
+			Inspect(decl, f)
+		}
+		inspectStmts(n.Stmts, f)
+	case *ExprStmt:
+		Inspect(n.X, f)
+	case *IfStmt:
+		Inspect(n.Cond, f)
+		Inspect(n.Then, f)
+		Inspect(n.Else, f)
+	case *WhileStmt:
+		Inspect(n.Cond, f)
+		Inspect(n.Body, f)
+	case *DoWhileStmt:
+		Inspect(n.Body, f)
+		Inspect(n.Cond, f)
+	case *ForStmt:
+		inspectExprs(n.Init, f)
+		Inspect(n.Cond, f)
+		inspectExprs(n.Update, f)
+		Inspect(n.Body, f)
+	case *SwitchStmt:
+		Inspect(n.Tag, f)
+		for _, clause := range n.Clauses {
+			Inspect(clause, f)
+		}
+	case *CaseClause:
+		Inspect(n.Value, f)
+		inspectStmts(n.Body, f)
+	case *ReturnStmt:
+		Inspect(n.Result, f)
+	case *PrintStmt:
+		inspectExprs(n.Args, f)
+	case *ScanStmt:
+		Inspect(n.Target, f)
+
+	case *ParenExpr:
+		Inspect(n.X, f)
+	case *UnaryExpr:
+		Inspect(n.X, f)
+	case *BinaryExpr:
+		Inspect(n.X, f)
+		Inspect(n.Y, f)
+	case *CastExpr:
+		Inspect(n.To, f)
+		Inspect(n.X, f)
+	case *ConversionExpr:
+		Inspect(n.X, f)
+	case *AssignExpr:
+		Inspect(n.Lhs, f)
+		Inspect(n.Rhs, f)
+	case *CallExpr:
+		Inspect(n.Fun, f)
+		inspectExprs(n.Args, f)
+	case *IndexExpr:
+		Inspect(n.Array, f)
+		inspectExprs(n.Indices, f)
+	case *MemberExpr:
+		Inspect(n.X, f)
+		Inspect(n.Member, f)
+	}
+}
+
+// A missing *Ident is a nil pointer, which isn't a nil `Node`.
+func inspectIdent(ident *Ident, f func(Node) bool) {
+	if ident != nil {
+		Inspect(ident, f)
+	}
+}
+
+func inspectExprs(list []Expr, f func(Node) bool) {
+	for _, e := range list {
+		Inspect(e, f)
+	}
+}
+
+func inspectStmts(list []Stmt, f func(Node) bool) {
+	for _, s := range list {
+		Inspect(s, f)
+	}
+}
@@ -0,0 +1,179 @@
+package cc0_error
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	FormatText = iota
+	FormatJSON
+	FormatSARIF
+)
+
+var formatNames = map[string]int{
+	"text":  FormatText,
+	"json":  FormatJSON,
+	"sarif": FormatSARIF,
+}
+
+func ParseFormat(name string) (int, bool) {
+	format, ok := formatNames[name]
+	return format, ok
+}
+
+// Diagnostics collects all the errors and warnings of a compilation so that they can be reported at once.
+type Diagnostics struct {
+	all       []*Error
+	nErrors   int
+	limit     int
+	truncated bool
+	file      string
+	format    int
+	source    []string
+
+	// Whether the warnings are reported as errors, as with `-Werror`
+	warningsAsErrors bool
+	enabledWarnings  map[int]bool // the categories which are enabled or disabled on the command line
+}
+
+// `file` is the name of the file being compiled. No more errors are collected after `limit` of them have been
+// reported, 0 means there is no limit.
+func NewDiagnostics(file string, limit int) *Diagnostics {
+	return &Diagnostics{all: []*Error{}, limit: limit, file: file, enabledWarnings: map[int]bool{}}
+}
+
+func (d *Diagnostics) SetFormat(format int) {
+	d.format = format
+}
+
+func (d *Diagnostics) SetWarningsAsErrors(warningsAsErrors bool) {
+	d.warningsAsErrors = warningsAsErrors
+}
+
+// With the source code, the text format shows the offending lines along with the messages.
+func (d *Diagnostics) SetSource(source string) {
+	d.source = strings.Split(source, "\n")
+}
+
+// Prints the line where the error starts and underlines the span of the error, like
+//
+//	x = y + 1;
+//	    ^
+func (d *Diagnostics) printSnippet(err *Error) {
+	if !err.HasPosition() || err.line > len(d.source) {
+		return
+	}
+	line := strings.TrimRight(d.source[err.line-1], "\r")
+	end := err.EndColumn()
+	if err.EndLine() > err.line {
+		end = len(line) + 1
+	}
+	var underline strings.Builder
+	for i := 0; i < err.column-1 && i < len(line); i++ {
+		// Keep the tabs so that the caret is aligned however wide they are displayed
+		if line[i] == '\t' {
+			underline.WriteByte('\t')
+		} else {
+			underline.WriteByte(' ')
+		}
+	}
+	underline.WriteByte('^')
+	for i := err.column + 1; i < end; i++ {
+		underline.WriteByte('~')
+	}
+	PrintlnToStdErr(line)
+	PrintlnToStdErr(underline.String())
+}
+
+func (d *Diagnostics) printText(err *Error) {
+	err.Report()
+	d.printSnippet(err)
+	for _, note := range err.notes {
+		note.Report()
+		d.printSnippet(note)
+	}
+}
+
+func (d *Diagnostics) Report(from int, err *Error) {
+	if d.ShouldStop() {
+		d.truncated = true
+		return
+	}
+	err.from = from
+	err.severity = SeverityError
+	d.all = append(d.all, err)
+	d.nErrors++
+}
+
+// Reports a warning of the category unless the category is disabled.
+func (d *Diagnostics) Warn(from, category int, err *Error) {
+	if !d.IsWarningEnabled(category) {
+		return
+	}
+	err.category = category
+	if d.warningsAsErrors {
+		d.Report(from, err)
+		return
+	}
+	err.from = from
+	err.severity = SeverityWarning
+	d.all = append(d.all, err)
+}
+
+func (d *Diagnostics) HasErrors() bool {
+	return d.nErrors > 0
+}
+
+// Tells the phases to give up once the error limit has been reached.
+func (d *Diagnostics) ShouldStop() bool {
+	return d.limit > 0 && d.nErrors >= d.limit
+}
+
+// Returns the collected errors and warnings sorted by their positions. The ones without a position come last.
+func (d *Diagnostics) All() []*Error {
+	sorted := make([]*Error, len(d.all))
+	copy(sorted, d.all)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		lhs, rhs := sorted[i], sorted[j]
+		if lhs.HasPosition() != rhs.HasPosition() {
+			return lhs.HasPosition()
+		}
+		if lhs.line != rhs.line {
+			return lhs.line < rhs.line
+		}
+		return lhs.column < rhs.column
+	})
+	return sorted
+}
+
+func (d *Diagnostics) PrintAll() {
+	switch d.format {
+	case FormatJSON:
+		d.printJSON(os.Stderr)
+	case FormatSARIF:
+		d.printSARIF(os.Stderr)
+	default:
+		for _, err := range d.All() {
+			d.printText(err)
+		}
+		if d.truncated || d.ShouldStop() {
+			PrintfToStdErr("Too many errors emitted (the limit is %d), stopping now.\n", d.limit)
+		}
+	}
+}
+
+// Prints everything collected so far and exits if there is any error. The summary of the failure is only printed
+// in the text format so that the machine-readable ones stay valid.
+func (d *Diagnostics) PrintAllAndExitOnError() {
+	d.PrintAll()
+	for _, err := range d.All() {
+		if err.severity == SeverityError {
+			if d.format == FormatText {
+				ThrowAndExit(err.from)
+			}
+			os.Exit(err.from)
+		}
+	}
+}
@@ -12,6 +12,17 @@ const (
 	Assembler
 )
 
+var phaseNames = map[int]string{
+	Source:    "Source",
+	Parser:    "Parser",
+	Analyzer:  "Analyzer",
+	Assembler: "Assembler",
+}
+
+func PhaseName(phase int) string {
+	return phaseNames[phase]
+}
+
 func ReportLineAndColumn(line, column int) {
 	PrintfToStdErr("At line %d, column %d: ", line, column)
 }
@@ -43,12 +54,6 @@ func throw(source int) {
 	PrintlnToStdErr(sourceMessage)
 }
 
-func ThrowButStayAlive(source int) {
-	PrintToStdErr("Warning: ")
-	throw(source)
-	PrintlnToStdErr("")
-}
-
 func ThrowAndExit(source int) {
 	PrintToStdErr("Fatal: ")
 	throw(source)
@@ -68,16 +73,146 @@ const (
 	UndefinedIdentifier
 	NoMain
 	AssignmentToConstant
+	UnknownInstruction
+	WrongOperandCount
+	OperandOutOfRange
+	MalformedOperand
+	MalformedConstant
+	MalformedFunctionBriefing
+	UndefinedLabel
+	MisplacedSection
+	UnexpectedLine
+	UnrecognizedCharacter
+	IllegalCharLiteral
+	IllegalStringLiteral
+	IllegalIntegerLiteral
+	IllegalDoubleLiteral
+	IntegerLiteralOutOfRange
+	IllegalCommentBlock
+	UnterminatedCommentBlock
+	UnexpectedTokens
+	JumpOutsideLoop
+	IllegalSwitchExpression
+	IllegalCaseLabel
+	DuplicateCaseLabel
+	IllegalArrayAccess
+	IllegalMemberAccess
+	IncompatibleStructs
+	MissingReturn
+	UnreachableCode
+	UnusedVariable
+	ShadowedIdentifier
+	ImplicitConversion
+	UnusedParameter
+	NarrowingConversion
+	ConflictingDeclaration
+	UndefinedFunction
+	VoidValue
+	IllegalArgument
+	ArgumentCountMismatch
+)
+
+// Stable names of the error codes for the machine-readable output.
+var codeNames = map[int]string{
+	Bug:                           "Bug",
+	NoMoreTokens:                  "NoMoreTokens",
+	IncompleteVariableDeclaration: "IncompleteVariableDeclaration",
+	InvalidDeclaration:            "InvalidDeclaration",
+	IncompleteExpression:          "IncompleteExpression",
+	IllegalExpression:             "IllegalExpression",
+	RedeclaredAnIdentifier:        "RedeclaredAnIdentifier",
+	InvalidStatement:              "InvalidStatement",
+	IncompleteFunctionCall:        "IncompleteFunctionCall",
+	UndefinedIdentifier:           "UndefinedIdentifier",
+	NoMain:                        "NoMain",
+	AssignmentToConstant:          "AssignmentToConstant",
+	UnknownInstruction:            "UnknownInstruction",
+	WrongOperandCount:             "WrongOperandCount",
+	OperandOutOfRange:             "OperandOutOfRange",
+	MalformedOperand:              "MalformedOperand",
+	MalformedConstant:             "MalformedConstant",
+	MalformedFunctionBriefing:     "MalformedFunctionBriefing",
+	UndefinedLabel:                "UndefinedLabel",
+	MisplacedSection:              "MisplacedSection",
+	UnexpectedLine:                "UnexpectedLine",
+	UnrecognizedCharacter:         "UnrecognizedCharacter",
+	IllegalCharLiteral:            "IllegalCharLiteral",
+	IllegalStringLiteral:          "IllegalStringLiteral",
+	IllegalIntegerLiteral:         "IllegalIntegerLiteral",
+	IllegalDoubleLiteral:          "IllegalDoubleLiteral",
+	IntegerLiteralOutOfRange:      "IntegerLiteralOutOfRange",
+	IllegalCommentBlock:           "IllegalCommentBlock",
+	UnterminatedCommentBlock:      "UnterminatedCommentBlock",
+	UnexpectedTokens:              "UnexpectedTokens",
+	JumpOutsideLoop:               "JumpOutsideLoop",
+	IllegalSwitchExpression:       "IllegalSwitchExpression",
+	IllegalCaseLabel:              "IllegalCaseLabel",
+	DuplicateCaseLabel:            "DuplicateCaseLabel",
+	IllegalArrayAccess:            "IllegalArrayAccess",
+	IllegalMemberAccess:           "IllegalMemberAccess",
+	IncompatibleStructs:           "IncompatibleStructs",
+	MissingReturn:                 "MissingReturn",
+	UnreachableCode:               "UnreachableCode",
+	UnusedVariable:                "UnusedVariable",
+	ShadowedIdentifier:            "ShadowedIdentifier",
+	ImplicitConversion:            "ImplicitConversion",
+	UnusedParameter:               "UnusedParameter",
+	NarrowingConversion:           "NarrowingConversion",
+	ConflictingDeclaration:        "ConflictingDeclaration",
+	UndefinedFunction:             "UndefinedFunction",
+	VoidValue:                     "VoidValue",
+	IllegalArgument:               "IllegalArgument",
+	ArgumentCountMismatch:         "ArgumentCountMismatch",
+}
+
+func CodeName(code int) string {
+	if name, ok := codeNames[code]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+const (
+	SeverityError = iota
+	SeverityWarning
+	SeverityNote
+	SeverityHint
 )
 
 type Error struct {
-	code   int
-	line   int
-	column int
+	code      int
+	line      int
+	column    int
+	endLine   int
+	endColumn int
+	message   string
+	severity  int
+	from      int
+	notes     []*Error
+	category  int // of the warning, or 0 if it is not a warning, which stays when the warning is made an error
 }
 
 func Of(code int) *Error {
-	return &Error{code, 0, 0}
+	return &Error{code: code}
+}
+
+// Notes point at other places related to the error, e.g. the previous declaration of a redeclared identifier.
+func Note(format string, args ...interface{}) *Error {
+	return &Error{code: Bug, message: fmt.Sprintf(format, args...), severity: SeverityNote}
+}
+
+// Hints are suggestions on how to fix the error.
+func Hint(format string, args ...interface{}) *Error {
+	return &Error{code: Bug, message: fmt.Sprintf(format, args...), severity: SeverityHint}
+}
+
+func (error *Error) WithNote(note *Error) *Error {
+	error.notes = append(error.notes, note)
+	return error
+}
+
+func (error *Error) Notes() []*Error {
+	return error.notes
 }
 
 func (error *Error) On(line, column int) *Error {
@@ -86,9 +221,95 @@ func (error *Error) On(line, column int) *Error {
 	return error
 }
 
+func (error *Error) Code() int {
+	return error.code
+}
+
+func (error *Error) Line() int {
+	return error.line
+}
+
+func (error *Error) Column() int {
+	return error.column
+}
+
+// The end of the span is right after the last character of the problematic code. Without being set explicitly,
+// the span is empty and the end is the same as the start.
+func (error *Error) Until(line, column int) *Error {
+	error.endLine = line
+	error.endColumn = column
+	return error
+}
+
+func (error *Error) HasSpan() bool {
+	return error.endLine > 0
+}
+
+func (error *Error) EndLine() int {
+	if !error.HasSpan() {
+		return error.line
+	}
+	return error.endLine
+}
+
+func (error *Error) EndColumn() int {
+	if !error.HasSpan() {
+		return error.column
+	}
+	return error.endColumn
+}
+
+func (error *Error) Severity() int {
+	return error.severity
+}
+
+// Returns the phase which reported the error, one of `Source`, `Parser`, `Analyzer` and `Assembler`.
+func (error *Error) From() int {
+	return error.from
+}
+
+// The option which enables the warning, e.g. "-Wshadow", or "" if it is not a warning.
+func (error *Error) Option() string {
+	if error.category == 0 {
+		return ""
+	}
+	return WarningOption(error.category)
+}
+
+func (error *Error) HasPosition() bool {
+	return error.line > 0
+}
+
+// Replaces the default message of the error code with a more specific one.
+func (error *Error) WithMessage(format string, args ...interface{}) *Error {
+	error.message = fmt.Sprintf(format, args...)
+	return error
+}
+
+func (error *Error) Report() {
+	if error.HasPosition() {
+		ReportLineAndColumn(error.line, error.column)
+	}
+	switch error.severity {
+	case SeverityWarning:
+		PrintToStdErr("Warning: ")
+	case SeverityNote:
+		PrintToStdErr("Note: ")
+	case SeverityHint:
+		PrintToStdErr("Hint: ")
+	}
+	switch {
+	case error.category == 0:
+		PrintlnToStdErr(error.Error())
+	case error.severity == SeverityError:
+		PrintfToStdErr("%s [-Werror,%s]\n", error.Error(), error.Option())
+	default:
+		PrintfToStdErr("%s [%s]\n", error.Error(), error.Option())
+	}
+}
+
 func (error *Error) DieAndReportPosition(from int) {
-	ReportLineAndColumn(error.line, error.column)
-	PrintlnToStdErr(error.Error())
+	error.Report()
 	ThrowAndExit(from)
 }
 
@@ -98,6 +319,9 @@ func (error *Error) Die(from int) {
 }
 
 func (error *Error) Error() string {
+	if error.message != "" {
+		return error.message
+	}
 	switch error.code {
 	case IncompleteVariableDeclaration:
 		return "The variable declaration is incomplete."
@@ -121,6 +345,80 @@ func (error *Error) Error() string {
 		return "No main function is defined."
 	case AssignmentToConstant:
 		return "Cannot assign a new value to a constant."
+	case UnknownInstruction:
+		return "Unknown instruction."
+	case WrongOperandCount:
+		return "Wrong number of operands for the instruction."
+	case OperandOutOfRange:
+		return "The operand does not fit in the width of the instruction."
+	case MalformedOperand:
+		return "The operand is not an integer."
+	case MalformedConstant:
+		return "The constant is not complying with the syntax."
+	case MalformedFunctionBriefing:
+		return "The function briefing is not complying with the syntax."
+	case UndefinedLabel:
+		return "Cannot jump to an undefined label."
+	case MisplacedSection:
+		return "The section is missing or out of order."
+	case UnexpectedLine:
+		return "Unexpected line outside of any section."
+	case UnrecognizedCharacter:
+		return "Unrecognized character."
+	case IllegalCharLiteral:
+		return "Illegal character literal."
+	case IllegalStringLiteral:
+		return "Illegal string literal."
+	case IllegalIntegerLiteral:
+		return "Illegal integer literal."
+	case IllegalDoubleLiteral:
+		return "Failed to parse double value."
+	case IntegerLiteralOutOfRange:
+		return "The integer literal does not fit in an int and keeps its lowest 32 bits."
+	case IllegalCommentBlock:
+		return "Encountered an illegal comment block."
+	case UnterminatedCommentBlock:
+		return "The comment block is not terminated."
+	case UnexpectedTokens:
+		return "Expected a function definition."
+	case JumpOutsideLoop:
+		return "A jump statement can only be used inside a loop."
+	case IllegalSwitchExpression:
+		return "The expression of a 'switch' statement must be an int or a char."
+	case IllegalCaseLabel:
+		return "A case label must be an integer or a char literal."
+	case DuplicateCaseLabel:
+		return "The case label is duplicated."
+	case IllegalArrayAccess:
+		return "The array is not accessed with the right subscripts."
+	case IllegalMemberAccess:
+		return "The member cannot be accessed."
+	case IncompatibleStructs:
+		return "The structs are not of the same type."
+	case MissingReturn:
+		return "The function may reach its end without returning a value."
+	case UnreachableCode:
+		return "The code can never be executed."
+	case UnusedVariable:
+		return "The variable is never used."
+	case ShadowedIdentifier:
+		return "The declaration hides another one of the same name."
+	case ImplicitConversion:
+		return "The value is converted to another type implicitly."
+	case UnusedParameter:
+		return "The parameter is never used."
+	case NarrowingConversion:
+		return "The value is converted to a narrower type implicitly."
+	case ConflictingDeclaration:
+		return "The declaration does not match the previous one."
+	case UndefinedFunction:
+		return "The function is declared but never defined."
+	case VoidValue:
+		return "A void value cannot be used."
+	case IllegalArgument:
+		return "The argument cannot be passed as the parameter."
+	case ArgumentCountMismatch:
+		return "The number of the arguments does not match the one of the parameters."
 	default:
 		return "An unknown error occurred."
 	}
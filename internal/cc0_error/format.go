@@ -0,0 +1,192 @@
+package cc0_error
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+var severityNames = map[int]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityNote:    "note",
+	SeverityHint:    "hint",
+}
+
+func SeverityName(severity int) string {
+	return severityNames[severity]
+}
+
+type jsonDiagnostic struct {
+	Severity    string     `json:"severity"`
+	Code        string     `json:"code"`
+	Message     string     `json:"message"`
+	File        string     `json:"file"`
+	StartLine   int        `json:"startLine"`
+	StartColumn int        `json:"startColumn"`
+	EndLine     int        `json:"endLine"`
+	EndColumn   int        `json:"endColumn"`
+	Phase       string     `json:"phase"`
+	Option      string     `json:"option,omitempty"` // which enables the warning, e.g. "-Wshadow"
+	Notes       []jsonNote `json:"notes,omitempty"`
+}
+
+type jsonNote struct {
+	Severity    string `json:"severity"`
+	Message     string `json:"message,omitempty"`
+	StartLine   int    `json:"startLine,omitempty"`
+	StartColumn int    `json:"startColumn,omitempty"`
+	EndLine     int    `json:"endLine,omitempty"`
+	EndColumn   int    `json:"endColumn,omitempty"`
+}
+
+func (d *Diagnostics) printJSON(w io.Writer) {
+	records := []jsonDiagnostic{}
+	for _, err := range d.All() {
+		notes := []jsonNote{}
+		for _, note := range err.notes {
+			notes = append(notes, jsonNote{
+				Severity:    severityNames[note.severity],
+				Message:     note.Error(),
+				StartLine:   note.line,
+				StartColumn: note.column,
+				EndLine:     note.EndLine(),
+				EndColumn:   note.EndColumn(),
+			})
+		}
+		records = append(records, jsonDiagnostic{
+			Severity:    severityNames[err.severity],
+			Code:        CodeName(err.code),
+			Message:     err.Error(),
+			File:        d.file,
+			StartLine:   err.line,
+			StartColumn: err.column,
+			EndLine:     err.EndLine(),
+			EndColumn:   err.EndColumn(),
+			Phase:       PhaseName(err.from),
+			Option:      err.Option(),
+			Notes:       notes,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(records)
+}
+
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html for the format.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string            `json:"ruleId"`
+	Level            string            `json:"level"`
+	Message          sarifMessage      `json:"message"`
+	Locations        []sarifLocation   `json:"locations"`
+	RelatedLocations []sarifLocation   `json:"relatedLocations,omitempty"`
+	Properties       map[string]string `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+func (d *Diagnostics) sarifLocationOf(err *Error) sarifLocation {
+	location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: d.file},
+	}}
+	if err.HasPosition() {
+		location.PhysicalLocation.Region = &sarifRegion{
+			StartLine:   err.line,
+			StartColumn: err.column,
+			EndLine:     err.EndLine(),
+			EndColumn:   err.EndColumn(),
+		}
+	}
+	return location
+}
+
+func (d *Diagnostics) printSARIF(w io.Writer) {
+	results := []sarifResult{}
+	ruleIDs := map[string]bool{}
+	for _, err := range d.All() {
+		// SARIF has no levels for notes and hints, they are attached to the result as related locations
+		related := []sarifLocation{}
+		for _, note := range err.notes {
+			location := d.sarifLocationOf(note)
+			location.Message = &sarifMessage{Text: note.Error()}
+			related = append(related, location)
+		}
+		ruleIDs[CodeName(err.code)] = true
+		properties := map[string]string{"phase": PhaseName(err.from)}
+		if err.category != 0 {
+			properties["option"] = err.Option()
+		}
+		results = append(results, sarifResult{
+			RuleID:           CodeName(err.code),
+			Level:            severityNames[err.severity],
+			Message:          sarifMessage{Text: err.Error()},
+			Locations:        []sarifLocation{d.sarifLocationOf(err)},
+			RelatedLocations: related,
+			Properties:       properties,
+		})
+	}
+
+	rules := []sarifRule{}
+	for id := range ruleIDs {
+		rules = append(rules, sarifRule{ID: id})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "cc0", Rules: rules}},
+			Results: results,
+		}},
+	})
+}
@@ -0,0 +1,64 @@
+package cc0_error
+
+// The categories of the warnings, which are enabled and disabled one by one with `-Wname` and `-Wno-name`.
+const (
+	WarnUnusedVariable = iota + 1
+	WarnShadow
+	WarnImplicitConversion
+	WarnUnusedParameter
+	WarnUnreachable
+	WarnLiteralRange
+	WarnReturnType
+	WarnConversion
+)
+
+var warningNames = map[int]string{
+	WarnUnusedVariable:     "unused-variable",
+	WarnShadow:             "shadow",
+	WarnImplicitConversion: "implicit-conversion",
+	WarnUnusedParameter:    "unused-parameter",
+	WarnUnreachable:        "unreachable",
+	WarnLiteralRange:       "literal-range",
+	WarnReturnType:         "return-type",
+	WarnConversion:         "conversion",
+}
+
+// The categories which are enabled unless they are disabled on the command line. The others are either too noisy for
+// most of the programs, e.g. every int added to a double is an implicit conversion, or only matter to some.
+var enabledByDefault = map[int]bool{
+	WarnUnusedVariable: true,
+	WarnUnreachable:    true,
+	WarnLiteralRange:   true,
+	WarnReturnType:     true,
+	WarnConversion:     true,
+}
+
+func ParseWarning(name string) (int, bool) {
+	for category, categoryName := range warningNames {
+		if categoryName == name {
+			return category, true
+		}
+	}
+	return 0, false
+}
+
+func WarningName(category int) string {
+	return warningNames[category]
+}
+
+// The option which enables the category, e.g. "-Wshadow".
+func WarningOption(category int) string {
+	return "-W" + warningNames[category]
+}
+
+// Enables or disables the warnings of the category, which are otherwise reported as `enabledByDefault` says.
+func (d *Diagnostics) SetWarningEnabled(category int, enabled bool) {
+	d.enabledWarnings[category] = enabled
+}
+
+func (d *Diagnostics) IsWarningEnabled(category int) bool {
+	if enabled, ok := d.enabledWarnings[category]; ok {
+		return enabled
+	}
+	return enabledByDefault[category]
+}
@@ -0,0 +1,143 @@
+// Package cfg splits the instructions of a function into basic blocks, which are linked by where the execution may go
+// after each of them, and drops the blocks that can never run.
+package cfg
+
+import "c0_compiler/internal/instruction"
+
+type Line = instruction.Line
+
+// A run of lines which is only entered at its first line and only left after its last line.
+type Block struct {
+	Index      int
+	Start, End int // the block has the lines [Start, End) of the function
+	Successors []*Block
+
+	// Whether the execution may go past the last line of the function after the block, which only happens to the code
+	// initializing the global variables, and to a function before its trailing return is appended
+	ReachesTheEnd bool
+}
+
+type Graph struct {
+	Lines  []Line
+	Blocks []*Block // in the order of the lines, the first one is where the function starts
+}
+
+func targetOf(line *Line) int {
+	return (*line.Operands)[0]
+}
+
+// Whether the execution never goes on to the next line after the line.
+func endsABlock(line *Line) bool {
+	return instruction.IsAJump(line.I.Code) || instruction.IsAReturn(line.I.Code)
+}
+
+// A block starts at the first line, at the targets of the jumps and after the jumps and the returns.
+func Build(lines []Line) *Graph {
+	// The end of the function is taken as a leader as well, so that the last block ends there
+	isALeader := make([]bool, len(lines)+1)
+	isALeader[0], isALeader[len(lines)] = true, true
+	for index := range lines {
+		line := &lines[index]
+		if instruction.IsAJump(line.I.Code) {
+			isALeader[targetOf(line)] = true
+		}
+		if endsABlock(line) {
+			isALeader[index+1] = true
+		}
+	}
+
+	g := &Graph{Lines: lines}
+	blockAt := make([]*Block, len(lines)+1)
+	for start := 0; start < len(lines); {
+		end := start + 1
+		for !isALeader[end] {
+			end++
+		}
+		block := &Block{Index: len(g.Blocks), Start: start, End: end}
+		g.Blocks = append(g.Blocks, block)
+		blockAt[start] = block
+		start = end
+	}
+
+	for _, block := range g.Blocks {
+		last := &lines[block.End-1]
+		var next []int
+		switch {
+		case last.I.Code == instruction.Jmp:
+			next = []int{targetOf(last)}
+		case instruction.IsAJump(last.I.Code):
+			next = []int{block.End, targetOf(last)}
+		case !instruction.IsAReturn(last.I.Code):
+			next = []int{block.End}
+		}
+		for _, offset := range next {
+			if offset == len(lines) {
+				block.ReachesTheEnd = true
+			} else if !containsBlock(block.Successors, blockAt[offset]) {
+				block.Successors = append(block.Successors, blockAt[offset])
+			}
+		}
+	}
+	return g
+}
+
+func containsBlock(blocks []*Block, block *Block) bool {
+	for _, b := range blocks {
+		if b == block {
+			return true
+		}
+	}
+	return false
+}
+
+// Which of the blocks can be run from the start of the function, by their indices.
+func (g *Graph) Reachable() []bool {
+	reachable := make([]bool, len(g.Blocks))
+	if len(g.Blocks) == 0 {
+		return reachable
+	}
+	reachable[0] = true
+	for stack := []*Block{g.Blocks[0]}; len(stack) > 0; {
+		block := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, successor := range block.Successors {
+			if !reachable[successor.Index] {
+				reachable[successor.Index] = true
+				stack = append(stack, successor)
+			}
+		}
+	}
+	return reachable
+}
+
+// Whether the execution may go past the last line, e.g. when a function has no return statement on some path.
+func (g *Graph) ReachesTheEnd() bool {
+	if len(g.Blocks) == 0 {
+		return true
+	}
+	reachable := g.Reachable()
+	for _, block := range g.Blocks {
+		if reachable[block.Index] && block.ReachesTheEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// Drops the lines of the blocks that can never run, e.g. a `jmp` after a return statement, and points the jumps to the
+// lines that are kept.
+func RemoveUnreachableCode(fn *instruction.Fn) {
+	lines := *fn.GetLines()
+	g := Build(lines)
+	reachable := g.Reachable()
+	isKept := make([]bool, len(lines))
+	for _, block := range g.Blocks {
+		for index := block.Start; index < block.End; index++ {
+			isKept[index] = reachable[block.Index]
+		}
+	}
+
+	if result, changed := instruction.RemoveLines(lines, func(index int) bool { return isKept[index] }); changed {
+		fn.SetLines(result)
+	}
+}
@@ -0,0 +1,186 @@
+// Package codegen emits the instructions of a checked syntax tree into the functions of its symbol table, which are
+// then assembled by the assembler.
+package codegen
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/cfg"
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/token"
+)
+
+// All the state of generating the code of a single program.
+type generator struct {
+	globalSymbolTable, currentSymbolTable *instruction.SymbolTable
+	currentFunction                       *instruction.Fn
+
+	// The loops and switches enclosing the statement being generated, the innermost one is the last
+	jumpContexts []*jumpContext
+}
+
+// The tree should have been checked without errors, so that all the identifiers are bound to their symbols and all
+// the expressions have their types.
+func Generate(program *ast.Program, globalSymbolTable *instruction.SymbolTable) {
+	g := &generator{
+		globalSymbolTable:  globalSymbolTable,
+		currentSymbolTable: globalSymbolTable,
+		currentFunction:    globalSymbolTable.RelatedFunction,
+	}
+	for _, decl := range program.Decls {
+		switch decl := decl.(type) {
+		case *ast.VarDecl:
+			g.generateVariableDeclaration(decl)
+		case *ast.FuncDecl:
+			if !decl.IsAPrototype {
+				g.generateFunctionDefinition(decl)
+			}
+		}
+	}
+}
+
+func (g *generator) generateFunctionDefinition(decl *ast.FuncDecl) {
+	fn := decl.Name.Symbol.FnInfo
+	g.currentFunction, g.currentSymbolTable = fn, fn.RelatedSymbolTable
+	for _, varDecl := range decl.Body.Decls {
+		g.generateVariableDeclaration(varDecl)
+	}
+	// The slots of the nested blocks are pushed at once after the ones of the body, since the blocks may be in loops
+	if size := fn.FrameSize() - slotsTakenBy(fn.RelatedSymbolTable); size > 0 {
+		fn.Append(instruction.Snew, size)
+	}
+	g.allocateArraysOfNestedBlocks(decl.Body)
+	for _, stmt := range decl.Body.Stmts {
+		g.generateStatement(stmt)
+	}
+
+	// Returns when the end of the function is reached without a return statement, which is left out when every path
+	// returns
+	if cfg.Build(*fn.GetLines()).ReachesTheEnd() {
+		switch fn.ReturnType {
+		case token.Void:
+			fn.Append(instruction.Ret)
+		case token.Int, token.Char:
+			fn.Append(instruction.Ipush, 0)
+			fn.Append(instruction.Iret)
+		case token.Double:
+			fn.Append(instruction.Snew, 2)
+			fn.Append(instruction.Dret)
+		}
+	}
+	cfg.RemoveUnreachableCode(fn)
+	g.currentFunction, g.currentSymbolTable = g.globalSymbolTable.RelatedFunction, g.globalSymbolTable
+}
+
+// The slots of the variables are pushed in the order of their declarations, which is how their addresses are given.
+func (g *generator) generateVariableDeclaration(decl *ast.VarDecl) {
+	sb := decl.Name.Symbol
+	switch {
+	case sb.IsACompileTimeConstant():
+		// Takes no slot
+	case sb.IsAnArray():
+		// The slot of the array gets the address of the elements
+		g.currentFunction.Append(instruction.Ipush, elementSlotsOf(sb))
+		g.currentFunction.Append(instruction.New)
+	case sb.Kind == token.Struct:
+		g.currentFunction.Append(instruction.Snew, sb.Size())
+		if decl.Init != nil {
+			g.copyStruct(g.locationOf(sb), g.locationOfMember(decl.Init))
+		}
+	case decl.Init == nil:
+		g.currentFunction.Append(instruction.Snew, sb.Size())
+	default:
+		if sb.Kind == token.Double {
+			g.currentFunction.Append(instruction.Snew, 2)
+		} else {
+			g.currentFunction.Append(instruction.Ipush, 0)
+		}
+		g.currentFunction.Append(instruction.Loada, g.currentSymbolTable.GetLevelDiff(sb), sb.Address)
+		g.generateExpression(decl.Init)
+		g.currentFunction.Append(storeInstructionOf(sb.Kind))
+	}
+}
+
+// The number of the slots taken by the elements of an array in the heap.
+func elementSlotsOf(sb *instruction.Symbol) int {
+	size := 1
+	for _, length := range sb.Dimensions {
+		size *= length
+	}
+	if sb.Kind == token.Double {
+		size *= 2
+	}
+	return size
+}
+
+// The end of the slots of the parameters and the variables of the table.
+func slotsTakenBy(table *instruction.SymbolTable) (end int) {
+	for _, sb := range table.Symbols {
+		if sb.Address >= 0 && sb.Address+sb.Size() > end {
+			end = sb.Address + sb.Size()
+		}
+	}
+	return
+}
+
+// The elements of the arrays of the nested blocks are allocated once when the function starts, rather than each time
+// a block is entered, and their slots are never taken by another block.
+func (g *generator) allocateArraysOfNestedBlocks(body *ast.BlockStmt) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		if block, ok := node.(*ast.BlockStmt); ok && block != body {
+			for _, decl := range block.Decls {
+				if sb := decl.Name.Symbol; sb.IsAnArray() {
+					g.currentFunction.Append(instruction.Loada, 0, sb.Address)
+					g.currentFunction.Append(instruction.Ipush, elementSlotsOf(sb))
+					g.currentFunction.Append(instruction.New)
+					g.currentFunction.Append(instruction.Istore)
+				}
+			}
+		}
+		return true
+	})
+}
+
+// The variable of a nested block already has its slot, which may have been taken by another block before, so it is
+// initialized by stores instead of pushes. The variables without an initializer are left as the slot is.
+func (g *generator) generateBlockVariableDeclaration(decl *ast.VarDecl) {
+	sb := decl.Name.Symbol
+	switch {
+	case sb.IsACompileTimeConstant(), sb.IsAnArray():
+		// Takes no slot, or has been allocated when the function starts
+	case decl.Init == nil:
+	case sb.Kind == token.Struct:
+		g.copyStruct(g.locationOf(sb), g.locationOfMember(decl.Init))
+	default:
+		g.currentFunction.Append(instruction.Loada, g.currentSymbolTable.GetLevelDiff(sb), sb.Address)
+		g.generateExpression(decl.Init)
+		g.currentFunction.Append(storeInstructionOf(sb.Kind))
+	}
+}
+
+func loadInstructionOf(kind int) int {
+	if kind == token.Double {
+		return instruction.Dload
+	}
+	return instruction.Iload
+}
+
+func storeInstructionOf(kind int) int {
+	if kind == token.Double {
+		return instruction.Dstore
+	}
+	return instruction.Istore
+}
+
+func arrayLoadInstructionOf(kind int) int {
+	if kind == token.Double {
+		return instruction.Daload
+	}
+	return instruction.Iaload
+}
+
+func arrayStoreInstructionOf(kind int) int {
+	if kind == token.Double {
+		return instruction.Dastore
+	}
+	return instruction.Iastore
+}
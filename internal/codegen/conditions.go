@@ -0,0 +1,112 @@
+package codegen
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/token"
+)
+
+// The code of a condition falls through when the condition stands, and the jumps in `falseJumps` are taken when it
+// doesn't. The other jumps go to where the condition stands, that is, right after its code. The last line of the
+// code is always the last one of `falseJumps`.
+type condition struct {
+	trueJumps  []int
+	falseJumps []int
+}
+
+var invertedJumps = map[int]int{
+	instruction.Je:  instruction.Jne,
+	instruction.Jne: instruction.Je,
+	instruction.Jl:  instruction.Jge,
+	instruction.Jge: instruction.Jl,
+	instruction.Jg:  instruction.Jle,
+	instruction.Jle: instruction.Jg,
+}
+
+// The jumps taken when the relations don't stand
+var jumpsOfRelations = map[int]int{
+	token.LessThan:           instruction.Jge,
+	token.LessThanOrEqual:    instruction.Jg,
+	token.EqualTo:            instruction.Jne,
+	token.GreaterThanOrEqual: instruction.Jl,
+	token.GreaterThan:        instruction.Jle,
+	token.NotEqualTo:         instruction.Je,
+}
+
+// Inverts the last jump of the condition, so that it is taken when the condition stands.
+func (g *generator) invertLastJumpOf(c *condition) (lastJump int) {
+	lastJump = c.falseJumps[len(c.falseJumps)-1]
+	c.falseJumps = c.falseJumps[:len(c.falseJumps)-1]
+	line := (*g.currentFunction.GetLines())[lastJump]
+	g.currentFunction.ChangeInstructionTo(lastJump, invertedJumps[line.I.Code], (*line.Operands)...)
+	return
+}
+
+func (g *generator) patchJumps(jumps []int, target int) {
+	lines := *g.currentFunction.GetLines()
+	for _, offset := range jumps {
+		lines[offset].SetFirstOperandTo(target)
+	}
+}
+
+// Returns the jumps which are taken when the condition doesn't stand, whose targets are left for the caller. The code
+// falls through when the condition stands.
+func (g *generator) generateCondition(x ast.Expr) []int {
+	c := g.generateLogicalCondition(x)
+	g.patchJumps(c.trueJumps, g.currentFunction.GetCurrentOffset())
+	return c.falseJumps
+}
+
+func (g *generator) generateLogicalCondition(x ast.Expr) *condition {
+	switch e := x.(type) {
+	case *ast.ParenExpr:
+		return g.generateLogicalCondition(e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.LogicalNot {
+			c := g.generateLogicalCondition(e.X)
+			lastJump := g.invertLastJumpOf(c)
+			return &condition{c.falseJumps, append(c.trueJumps, lastJump)}
+		}
+	case *ast.BinaryExpr:
+		switch {
+		case e.Op == token.LogicalOr:
+			// The right hand side is skipped once the left hand side stands
+			c := g.generateLogicalCondition(e.X)
+			c.trueJumps = append(c.trueJumps, g.invertLastJumpOf(c))
+			g.patchJumps(c.falseJumps, g.currentFunction.GetCurrentOffset())
+			rhs := g.generateLogicalCondition(e.Y)
+			return &condition{append(c.trueJumps, rhs.trueJumps...), rhs.falseJumps}
+		case e.Op == token.LogicalAnd:
+			// The right hand side is skipped once the left hand side doesn't stand
+			c := g.generateLogicalCondition(e.X)
+			g.patchJumps(c.trueJumps, g.currentFunction.GetCurrentOffset())
+			rhs := g.generateLogicalCondition(e.Y)
+			return &condition{rhs.trueJumps, append(c.falseJumps, rhs.falseJumps...)}
+		case isARelationalOperator(e.Op):
+			g.generateExpression(e.X)
+			g.generateExpression(e.Y)
+			if e.X.Type().Kind == token.Double {
+				g.currentFunction.Append(instruction.Dcmp)
+			} else {
+				g.currentFunction.Append(instruction.Icmp)
+			}
+			g.currentFunction.Append(jumpsOfRelations[e.Op], 0)
+			return &condition{nil, []int{g.currentFunction.GetCurrentOffset() - 1}}
+		}
+	}
+
+	// A value stands when it is not 0
+	g.generateExpression(x)
+	if x.Type().Kind == token.Double {
+		g.currentFunction.Append(instruction.Ipush, 0)
+		g.currentFunction.Append(instruction.I2d)
+		g.currentFunction.Append(instruction.Dcmp)
+	}
+	g.currentFunction.Append(instruction.Je, 0)
+	return &condition{nil, []int{g.currentFunction.GetCurrentOffset() - 1}}
+}
+
+func isARelationalOperator(kind int) bool {
+	_, ok := jumpsOfRelations[kind]
+	return ok
+}
@@ -0,0 +1,241 @@
+package codegen
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/token"
+)
+
+// A struct is kept in the consecutive slots of its variable, so where a member is, e.g. `p.from.x`, is known without
+// running the program, and a struct is copied slot by slot.
+type memberLocation struct {
+	levelDiff, address int
+	structType         *instruction.StructType // the type of the member if it is a struct
+}
+
+// Pushes the value of the expression, which is not a struct.
+func (g *generator) generateExpression(x ast.Expr) {
+	switch e := x.(type) {
+	case *ast.IntegerLiteral:
+		g.currentFunction.Append(instruction.Ipush, int(e.Value))
+	case *ast.DoubleLiteral:
+		address := g.globalSymbolTable.AddALiteral(instruction.ConstantKindDouble, e.Value)
+		g.currentFunction.Append(instruction.Loadc, -address)
+	case *ast.CharLiteral:
+		g.currentFunction.Append(instruction.Bipush, int(e.Value))
+	case *ast.ParenExpr:
+		g.generateExpression(e.X)
+	case *ast.Ident:
+		if e.Symbol.IsACompileTimeConstant() {
+			g.pushValueOf(e.Symbol)
+			return
+		}
+		g.currentFunction.Append(instruction.Loada, g.currentSymbolTable.GetLevelDiff(e.Symbol), e.Symbol.Address)
+		g.currentFunction.Append(loadInstructionOf(e.Symbol.Kind))
+	case *ast.UnaryExpr:
+		g.generateExpression(e.X)
+		switch {
+		case e.Op == token.BitwiseNot:
+			appendComplement(g.currentFunction)
+		case e.Op == token.MinusSign && e.Type().Kind == token.Double:
+			g.currentFunction.Append(instruction.Dneg)
+		case e.Op == token.MinusSign:
+			g.currentFunction.Append(instruction.Ineg)
+		}
+	case *ast.BinaryExpr:
+		g.generateExpression(e.X)
+		g.generateExpression(e.Y)
+		if !g.generateIntegerOperation(e.Op) {
+			g.currentFunction.Append(arithmeticInstructionOf(e.Op, e.Type().Kind))
+		}
+	case *ast.CastExpr:
+		g.generateExpression(e.X)
+		g.generateConversion(e.X.Type().Kind, e.To.Type.Kind)
+	case *ast.ConversionExpr:
+		g.generateExpression(e.X)
+		g.generateConversion(e.X.Type().Kind, e.Type().Kind)
+	case *ast.AssignExpr:
+		g.generateAssignment(e)
+	case *ast.CallExpr:
+		g.generateFunctionCall(e)
+	case *ast.IndexExpr:
+		g.generateElementAddress(e)
+		g.currentFunction.Append(arrayLoadInstructionOf(e.Type().Kind))
+	case *ast.MemberExpr:
+		location := g.locationOfMember(e)
+		g.currentFunction.Append(instruction.Loada, location.levelDiff, location.address)
+		g.currentFunction.Append(loadInstructionOf(e.Type().Kind))
+	}
+}
+
+// A constant whose value is known takes no slot, so its value is pushed instead, as the literals are.
+func (g *generator) pushValueOf(sb *instruction.Symbol) {
+	switch value := sb.Value.(type) {
+	case float64:
+		address := g.globalSymbolTable.AddALiteral(instruction.ConstantKindDouble, value)
+		g.currentFunction.Append(instruction.Loadc, -address)
+	case int32:
+		g.currentFunction.Append(instruction.Ipush, int(value))
+	}
+}
+
+// Applies the operator which has no instruction to the two ints on the stack, or returns false if it has one. The
+// operators which need the operands twice copy both of them by `dup2`, and the others call the functions of the
+// runtime, e.g. `a % b` is `a - a / b * b`, `a | b` is `a + b - (a & b)` and `a << n` is `a * _bit(n)`.
+func (g *generator) generateIntegerOperation(operator int) bool {
+	fn := g.currentFunction
+	switch operator {
+	case token.ModuloSign:
+		fn.Append(instruction.Dup2)
+		fn.Append(instruction.Idiv)
+		fn.Append(instruction.Imul)
+		fn.Append(instruction.Isub)
+	case token.BitwiseAnd:
+		fn.Append(instruction.Call, g.runtimeFunction("_and").Address)
+	case token.BitwiseOr:
+		fn.Append(instruction.Dup2)
+		fn.Append(instruction.Call, g.runtimeFunction("_and").Address)
+		fn.Append(instruction.Isub)
+		fn.Append(instruction.Iadd)
+	case token.BitwiseXor:
+		// `a + b - 2 * (a & b)`
+		fn.Append(instruction.Dup2)
+		fn.Append(instruction.Call, g.runtimeFunction("_and").Address)
+		fn.Append(instruction.Ipush, 2)
+		fn.Append(instruction.Imul)
+		fn.Append(instruction.Isub)
+		fn.Append(instruction.Iadd)
+	case token.LeftShift:
+		fn.Append(instruction.Call, g.runtimeFunction("_bit").Address)
+		fn.Append(instruction.Imul)
+	case token.RightShift:
+		fn.Append(instruction.Call, g.runtimeFunction("_shr").Address)
+	default:
+		return false
+	}
+	return true
+}
+
+func arithmeticInstructionOf(operator, kind int) int {
+	isDouble := kind == token.Double
+	switch {
+	case operator == token.PlusSign && isDouble:
+		return instruction.Dadd
+	case operator == token.PlusSign:
+		return instruction.Iadd
+	case operator == token.MinusSign && isDouble:
+		return instruction.Dsub
+	case operator == token.MinusSign:
+		return instruction.Isub
+	case operator == token.MultiplicationSign && isDouble:
+		return instruction.Dmul
+	case operator == token.MultiplicationSign:
+		return instruction.Imul
+	case isDouble:
+		return instruction.Ddiv
+	}
+	return instruction.Idiv
+}
+
+func (g *generator) generateConversion(source, dest int) {
+	switch {
+	case (source == token.Int || source == token.Char) && dest == token.Double:
+		g.currentFunction.Append(instruction.I2d)
+	case source == token.Double && dest == token.Int:
+		g.currentFunction.Append(instruction.D2i)
+	case source == token.Double && dest == token.Char:
+		g.currentFunction.Append(instruction.D2i)
+		g.currentFunction.Append(instruction.I2c)
+	case source == token.Int && dest == token.Char:
+		g.currentFunction.Append(instruction.I2c)
+	}
+}
+
+// Pushes the address of the elements and the index of the element referred by the subscripts, which are what
+// `xaload` and `xastore` take. The elements are laid out one dimension after another, e.g. `m[1][2]` of
+// `int m[3][4]` is the element 1 * 4 + 2, and each of the doubles takes 2 slots.
+func (g *generator) generateElementAddress(e *ast.IndexExpr) {
+	sb := e.Array.Symbol
+	g.currentFunction.Append(instruction.Loada, g.currentSymbolTable.GetLevelDiff(sb), sb.Address)
+	g.currentFunction.Append(instruction.Aload)
+	for index, subscript := range e.Indices {
+		if index > 0 {
+			g.currentFunction.Append(instruction.Ipush, sb.Dimensions[index])
+			g.currentFunction.Append(instruction.Imul)
+		}
+		g.generateExpression(subscript)
+		if index > 0 {
+			g.currentFunction.Append(instruction.Iadd)
+		}
+	}
+}
+
+func (g *generator) generateAssignment(e *ast.AssignExpr) {
+	if e.Lhs.Type().Kind == token.Struct {
+		g.copyStruct(g.locationOfMember(e.Lhs), g.locationOfMember(e.Rhs))
+		return
+	}
+	kind := e.Lhs.Type().Kind
+	if index, ok := e.Lhs.(*ast.IndexExpr); ok {
+		g.generateElementAddress(index)
+		g.generateExpression(e.Rhs)
+		g.currentFunction.Append(arrayStoreInstructionOf(kind))
+		return
+	}
+	location := g.locationOfMember(e.Lhs)
+	g.currentFunction.Append(instruction.Loada, location.levelDiff, location.address)
+	g.generateExpression(e.Rhs)
+	g.currentFunction.Append(storeInstructionOf(kind))
+}
+
+// Pushes the arguments and calls the function, whose value is left on the stack. A struct is passed by pushing all of
+// its slots.
+func (g *generator) generateFunctionCall(e *ast.CallExpr) {
+	for _, arg := range e.Args {
+		if arg.Type().Kind == token.Struct {
+			g.pushStruct(g.locationOfMember(arg))
+		} else {
+			g.generateExpression(arg)
+		}
+	}
+	g.currentFunction.Append(instruction.Call, e.Fun.Symbol.Address)
+}
+
+func (g *generator) locationOf(sb *instruction.Symbol) memberLocation {
+	return memberLocation{
+		levelDiff:  g.currentSymbolTable.GetLevelDiff(sb),
+		address:    sb.Address,
+		structType: sb.Struct,
+	}
+}
+
+// Where a variable or a member of a struct is, e.g. `p` or `p.from.x`.
+func (g *generator) locationOfMember(x ast.Expr) memberLocation {
+	switch e := ast.Unparen(x).(type) {
+	case *ast.MemberExpr:
+		location := g.locationOfMember(e.X)
+		location.address += e.Field.Address
+		location.structType = e.Field.Struct
+		return location
+	case *ast.Ident:
+		return g.locationOf(e.Symbol)
+	}
+	panic("only a variable or a member of a struct has a location")
+}
+
+func (g *generator) copyStruct(destination, source memberLocation) {
+	for offset := 0; offset < source.structType.Size; offset++ {
+		g.currentFunction.Append(instruction.Loada, destination.levelDiff, destination.address+offset)
+		g.currentFunction.Append(instruction.Loada, source.levelDiff, source.address+offset)
+		g.currentFunction.Append(instruction.Iload)
+		g.currentFunction.Append(instruction.Istore)
+	}
+}
+
+// Pushes all the slots of the struct, which is how a struct is passed to a function.
+func (g *generator) pushStruct(source memberLocation) {
+	for offset := 0; offset < source.structType.Size; offset++ {
+		g.currentFunction.Append(instruction.Loada, source.levelDiff, source.address+offset)
+		g.currentFunction.Append(instruction.Iload)
+	}
+}
@@ -0,0 +1,174 @@
+package codegen
+
+import (
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/token"
+)
+
+// The VM has no instructions for the bitwise operators and the shifts, so what the other instructions cannot do
+// inline is done by the functions of the runtime. Each of them is generated into the program when it is first called,
+// and is assembled as any other function. Their names start with '_', which no identifier does, so they cannot clash
+// with the functions of the program.
+
+// Returns the symbol of the function of the runtime, which is generated if it has not been.
+func (g *generator) runtimeFunction(name string) *instruction.Symbol {
+	if sb := g.globalSymbolTable.GetSymbolNamed(name); sb != nil {
+		return sb
+	}
+	fn := instruction.InitFn(token.Int)
+	w := &runtimeWriter{fn: fn, table: g.globalSymbolTable.AppendChildSymbolTable(fn)}
+	_ = g.globalSymbolTable.AddAFunction(name, token.Int, fn)
+	switch name {
+	case "_and":
+		generateBitwiseAnd(w)
+	case "_bit":
+		generatePowerOfTwo(w)
+	case "_shr":
+		generateRightShift(w, g.runtimeFunction("_bit"))
+	}
+	return g.globalSymbolTable.GetSymbolNamed(name)
+}
+
+// Writes the code of a function of the runtime, whose parameters and variables are all ints.
+type runtimeWriter struct {
+	fn    *instruction.Fn
+	table *instruction.SymbolTable
+}
+
+func (w *runtimeWriter) parameters(names ...string) {
+	for _, name := range names {
+		_ = w.table.AddAVariable(name, token.Int)
+		*w.fn.Parameters = append(*w.fn.Parameters, name)
+	}
+}
+
+// Declares the variables, which start as 0.
+func (w *runtimeWriter) variables(names ...string) {
+	for _, name := range names {
+		_ = w.table.AddAVariable(name, token.Int)
+	}
+	w.fn.Append(instruction.Snew, len(names))
+}
+
+func (w *runtimeWriter) load(name string) {
+	w.fn.Append(instruction.Loada, 0, w.table.GetAddressOf(name))
+	w.fn.Append(instruction.Iload)
+}
+
+func (w *runtimeWriter) push(value int) func() {
+	return func() {
+		w.fn.Append(instruction.Ipush, value)
+	}
+}
+
+// Stores the value pushed by `push` into the variable.
+func (w *runtimeWriter) store(name string, push func()) {
+	w.fn.Append(instruction.Loada, 0, w.table.GetAddressOf(name))
+	push()
+	w.fn.Append(instruction.Istore)
+}
+
+// Adds the value pushed by `push` to the variable.
+func (w *runtimeWriter) add(name string, push func()) {
+	w.store(name, func() {
+		w.load(name)
+		push()
+		w.fn.Append(instruction.Iadd)
+	})
+}
+
+// Appends the jump, whose target is left to `patch`.
+func (w *runtimeWriter) jump(jump int) (offset int) {
+	offset = w.fn.GetCurrentOffset()
+	w.fn.Append(jump, 0)
+	return
+}
+
+// Points the jump to the next line.
+func (w *runtimeWriter) patch(jump int) {
+	(*w.fn.GetLines())[jump].SetFirstOperandTo(w.fn.GetCurrentOffset())
+}
+
+// `~x`, which is `-x - 1` in two's complement.
+func appendComplement(fn *instruction.Fn) {
+	fn.Append(instruction.Ineg)
+	fn.Append(instruction.Ipush, 1)
+	fn.Append(instruction.Isub)
+}
+
+// `int _and(int a, int b)` takes the bits of the operands from the highest one, which is 1 when the operand is
+// negative, by doubling them, and shifts the bits that are both 1 into the result.
+func generateBitwiseAnd(w *runtimeWriter) {
+	w.parameters("a", "b")
+	w.variables("r", "i")
+	loop := w.fn.GetCurrentOffset()
+	w.add("r", func() { w.load("r") })
+	w.load("a")
+	aIsNotSet := w.jump(instruction.Jge)
+	w.load("b")
+	bIsNotSet := w.jump(instruction.Jge)
+	w.add("r", w.push(1))
+	w.patch(aIsNotSet)
+	w.patch(bIsNotSet)
+	w.add("a", func() { w.load("a") })
+	w.add("b", func() { w.load("b") })
+	w.add("i", w.push(1))
+	w.load("i")
+	w.fn.Append(instruction.Ipush, 32)
+	w.fn.Append(instruction.Isub)
+	w.fn.Append(instruction.Jl, loop)
+	w.load("r")
+	w.fn.Append(instruction.Iret)
+}
+
+// `int _bit(int n)` is 2 to the power of the lowest 5 bits of `n`, which is what the shifts of x86 count by. The power
+// of 31 wraps around to the smallest int.
+func generatePowerOfTwo(w *runtimeWriter) {
+	w.parameters("n")
+	w.variables("p")
+	w.store("n", func() {
+		w.load("n")
+		w.load("n")
+		w.fn.Append(instruction.Ipush, 32)
+		w.fn.Append(instruction.Idiv)
+		w.fn.Append(instruction.Ipush, 32)
+		w.fn.Append(instruction.Imul)
+		w.fn.Append(instruction.Isub)
+	})
+	w.load("n")
+	isNotNegative := w.jump(instruction.Jge)
+	w.add("n", w.push(32))
+	w.patch(isNotNegative)
+	w.store("p", w.push(1))
+	loop := w.fn.GetCurrentOffset()
+	w.load("n")
+	end := w.jump(instruction.Je)
+	w.add("p", func() { w.load("p") })
+	w.add("n", w.push(-1))
+	w.fn.Append(instruction.Jmp, loop)
+	w.patch(end)
+	w.load("p")
+	w.fn.Append(instruction.Iret)
+}
+
+// `int _shr(int a, int n)` shifts arithmetically, which divides by the power of 2 rounding down. `idiv` rounds
+// towards 0 instead, so a negative `a` is complemented before and after the division.
+func generateRightShift(w *runtimeWriter, powerOfTwo *instruction.Symbol) {
+	power := powerOfTwo.Address
+	w.parameters("a", "n")
+	w.load("a")
+	isNegative := w.jump(instruction.Jl)
+	w.load("a")
+	w.load("n")
+	w.fn.Append(instruction.Call, power)
+	w.fn.Append(instruction.Idiv)
+	w.fn.Append(instruction.Iret)
+	w.patch(isNegative)
+	w.load("a")
+	appendComplement(w.fn)
+	w.load("n")
+	w.fn.Append(instruction.Call, power)
+	w.fn.Append(instruction.Idiv)
+	appendComplement(w.fn)
+	w.fn.Append(instruction.Iret)
+}
@@ -0,0 +1,226 @@
+package codegen
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/token"
+)
+
+// The jumps made by the `break`s and `continue`s in a loop or a switch, which are backpatched once it is done.
+type jumpContext struct {
+	breaks    []int
+	continues []int
+	isASwitch bool // `continue` is not about switches
+}
+
+func (g *generator) enterLoop() {
+	g.jumpContexts = append(g.jumpContexts, &jumpContext{})
+}
+
+func (g *generator) enterSwitch() {
+	g.jumpContexts = append(g.jumpContexts, &jumpContext{isASwitch: true})
+}
+
+// Points the jumps of the innermost loop or switch to their targets, and leaves it.
+func (g *generator) leaveJumpContext(breakTarget, continueTarget int) {
+	context := g.jumpContexts[len(g.jumpContexts)-1]
+	g.jumpContexts = g.jumpContexts[:len(g.jumpContexts)-1]
+	g.patchJumps(context.breaks, breakTarget)
+	g.patchJumps(context.continues, continueTarget)
+}
+
+// Returns the innermost loop, which the type checking makes sure exists for a `continue`.
+func (g *generator) innermostLoop() *jumpContext {
+	for index := len(g.jumpContexts) - 1; index >= 0; index-- {
+		if !g.jumpContexts[index].isASwitch {
+			return g.jumpContexts[index]
+		}
+	}
+	return nil
+}
+
+func (g *generator) generateStatement(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		enclosingTable := g.currentSymbolTable
+		g.currentSymbolTable = s.Scope
+		for _, varDecl := range s.Decls {
+			g.generateBlockVariableDeclaration(varDecl)
+		}
+		for _, inner := range s.Stmts {
+			g.generateStatement(inner)
+		}
+		g.currentSymbolTable = enclosingTable
+	case *ast.ExprStmt:
+		g.generateExpressionStatement(s.X)
+	case *ast.IfStmt:
+		g.generateIfStatement(s)
+	case *ast.WhileStmt:
+		g.generateWhileStatement(s)
+	case *ast.DoWhileStmt:
+		g.generateDoWhileStatement(s)
+	case *ast.ForStmt:
+		g.generateForStatement(s)
+	case *ast.SwitchStmt:
+		g.generateSwitchStatement(s)
+	case *ast.BranchStmt:
+		g.generateBranchStatement(s)
+	case *ast.ReturnStmt:
+		g.generateReturnStatement(s)
+	case *ast.PrintStmt:
+		g.generatePrintStatement(s)
+	case *ast.ScanStmt:
+		g.generateScanStatement(s)
+	}
+}
+
+// An assignment or a function call, whose value is dropped.
+func (g *generator) generateExpressionStatement(x ast.Expr) {
+	switch e := ast.Unparen(x).(type) {
+	case *ast.AssignExpr:
+		g.generateAssignment(e)
+	case *ast.CallExpr:
+		g.generateFunctionCall(e)
+		switch e.Type().Kind {
+		case token.Double:
+			g.currentFunction.Append(instruction.Pop2)
+		case token.Int, token.Char:
+			g.currentFunction.Append(instruction.Pop)
+		}
+	}
+}
+
+func (g *generator) generateIfStatement(s *ast.IfStmt) {
+	falseJumps := g.generateCondition(s.Cond)
+	g.generateStatement(s.Then)
+	if s.Else == nil {
+		g.patchJumps(falseJumps, g.currentFunction.GetCurrentOffset())
+		return
+	}
+
+	offsetOfJumpOverElse := g.currentFunction.GetCurrentOffset()
+	g.currentFunction.Append(instruction.Jmp, 0)
+	g.patchJumps(falseJumps, g.currentFunction.GetCurrentOffset())
+	g.generateStatement(s.Else)
+	g.patchJumps([]int{offsetOfJumpOverElse}, g.currentFunction.GetCurrentOffset())
+}
+
+func (g *generator) generateWhileStatement(s *ast.WhileStmt) {
+	offsetOfCondition := g.currentFunction.GetCurrentOffset()
+	falseJumps := g.generateCondition(s.Cond)
+	g.enterLoop()
+	g.generateStatement(s.Body)
+	g.currentFunction.Append(instruction.Jmp, offsetOfCondition)
+	g.patchJumps(falseJumps, g.currentFunction.GetCurrentOffset())
+	g.leaveJumpContext(g.currentFunction.GetCurrentOffset(), offsetOfCondition)
+}
+
+func (g *generator) generateDoWhileStatement(s *ast.DoWhileStmt) {
+	offsetOfBody := g.currentFunction.GetCurrentOffset()
+	g.enterLoop()
+	g.generateStatement(s.Body)
+	offsetOfCondition := g.currentFunction.GetCurrentOffset()
+	falseJumps := g.generateCondition(s.Cond)
+	g.currentFunction.Append(instruction.Jmp, offsetOfBody)
+	g.patchJumps(falseJumps, g.currentFunction.GetCurrentOffset())
+	g.leaveJumpContext(g.currentFunction.GetCurrentOffset(), offsetOfCondition)
+}
+
+// The update is placed after the body, so that a `continue` goes to the update and the end of the body falls into
+// it.
+func (g *generator) generateForStatement(s *ast.ForStmt) {
+	for _, x := range s.Init {
+		g.generateExpressionStatement(x)
+	}
+	offsetOfCondition := g.currentFunction.GetCurrentOffset()
+	var falseJumps []int
+	if s.Cond != nil {
+		falseJumps = g.generateCondition(s.Cond)
+	}
+	g.enterLoop()
+	g.generateStatement(s.Body)
+	offsetOfUpdate := g.currentFunction.GetCurrentOffset()
+	for _, x := range s.Update {
+		g.generateExpressionStatement(x)
+	}
+	g.currentFunction.Append(instruction.Jmp, offsetOfCondition)
+	g.patchJumps(falseJumps, g.currentFunction.GetCurrentOffset())
+	g.leaveJumpContext(g.currentFunction.GetCurrentOffset(), offsetOfUpdate)
+}
+
+// The target is backpatched when the loop or the switch is done.
+func (g *generator) generateBranchStatement(s *ast.BranchStmt) {
+	g.currentFunction.Append(instruction.Jmp, 0)
+	offset := g.currentFunction.GetCurrentOffset() - 1
+	if s.Kind == token.Break {
+		context := g.jumpContexts[len(g.jumpContexts)-1]
+		context.breaks = append(context.breaks, offset)
+	} else {
+		loop := g.innermostLoop()
+		loop.continues = append(loop.continues, offset)
+	}
+}
+
+func (g *generator) generateReturnStatement(s *ast.ReturnStmt) {
+	if s.Result != nil {
+		g.generateExpression(s.Result)
+	}
+	switch g.currentFunction.ReturnType {
+	case token.Void:
+		g.currentFunction.Append(instruction.Ret)
+	case token.Double:
+		g.currentFunction.Append(instruction.Dret)
+	default:
+		g.currentFunction.Append(instruction.Iret)
+	}
+}
+
+// The values are separated by spaces, and the line is ended after them.
+func (g *generator) generatePrintStatement(s *ast.PrintStmt) {
+	for index, arg := range s.Args {
+		if index > 0 {
+			g.currentFunction.Append(instruction.Bipush, 32)
+			g.currentFunction.Append(instruction.Cprint)
+		}
+		if literal, ok := arg.(*ast.StringLiteral); ok {
+			address := g.globalSymbolTable.AddALiteral(instruction.ConstantKindString, literal.Value)
+			g.currentFunction.Append(instruction.Loadc, -address)
+			g.currentFunction.Append(instruction.Sprint)
+			continue
+		}
+		g.generateExpression(arg)
+		switch arg.Type().Kind {
+		case token.Double:
+			g.currentFunction.Append(instruction.Dprint)
+		case token.Char:
+			g.currentFunction.Append(instruction.Cprint)
+		default:
+			g.currentFunction.Append(instruction.Iprint)
+		}
+	}
+	g.currentFunction.Append(instruction.Printl)
+}
+
+func (g *generator) generateScanStatement(s *ast.ScanStmt) {
+	kind := s.Target.Type().Kind
+	index, isAnElement := s.Target.(*ast.IndexExpr)
+	if isAnElement {
+		g.generateElementAddress(index)
+	} else {
+		location := g.locationOfMember(s.Target)
+		g.currentFunction.Append(instruction.Loada, location.levelDiff, location.address)
+	}
+	switch kind {
+	case token.Int:
+		g.currentFunction.Append(instruction.Iscan)
+	case token.Char:
+		g.currentFunction.Append(instruction.Cscan)
+	case token.Double:
+		g.currentFunction.Append(instruction.Dscan)
+	}
+	if isAnElement {
+		g.currentFunction.Append(arrayStoreInstructionOf(kind))
+	} else {
+		g.currentFunction.Append(storeInstructionOf(kind))
+	}
+}
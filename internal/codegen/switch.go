@@ -0,0 +1,147 @@
+package codegen
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/instruction"
+	"sort"
+)
+
+// Labels are dispatched by a binary search on their ranges when there are at least this many of them, and they fill at
+// least half of the range between the smallest and the largest one. The jumps of the VM only take constant targets, so
+// the labels cannot be dispatched through a table of them.
+const minimumCasesOfBinarySearch = 4
+
+type caseLabel struct {
+	value  int
+	offset int // where the statements of the label start
+}
+
+// A range of consecutive values dispatched to the same target. The target of the ranges between the labels is the
+// default one.
+type caseRange struct {
+	from, to  int
+	target    int
+	isDefault bool
+}
+
+func (g *generator) generateSwitchStatement(s *ast.SwitchStmt) {
+	g.generateExpression(s.Tag)
+
+	// The value of the expression stays on the stack until it is dispatched, which is done after the body so that
+	// where the labels are is known.
+	offsetOfJumpToDispatch := g.currentFunction.GetCurrentOffset()
+	g.currentFunction.Append(instruction.Jmp, 0)
+
+	g.enterSwitch()
+	labels := []caseLabel{}
+	defaultTarget := -1
+	for _, clause := range s.Clauses {
+		if clause.IsDefault {
+			defaultTarget = g.currentFunction.GetCurrentOffset()
+		} else {
+			labels = append(labels, caseLabel{clause.Label, g.currentFunction.GetCurrentOffset()})
+		}
+		for _, stmt := range clause.Body {
+			g.generateStatement(stmt)
+		}
+	}
+	g.jumpToTheEndOfSwitch()
+
+	g.setJumpTarget(offsetOfJumpToDispatch, g.currentFunction.GetCurrentOffset())
+	g.dispatchCases(labels, defaultTarget)
+	g.leaveJumpContext(g.currentFunction.GetCurrentOffset(), 0)
+}
+
+// Jumps to the end of the innermost switch, which is backpatched when the switch is done.
+func (g *generator) jumpToTheEndOfSwitch() {
+	context := g.jumpContexts[len(g.jumpContexts)-1]
+	g.currentFunction.Append(instruction.Jmp, 0)
+	context.breaks = append(context.breaks, g.currentFunction.GetCurrentOffset()-1)
+}
+
+// Drops the value being dispatched and jumps to `target`, or to the end of the switch if `target` is negative.
+func (g *generator) popAndJumpTo(target int) {
+	g.currentFunction.Append(instruction.Pop)
+	if target < 0 {
+		g.jumpToTheEndOfSwitch()
+	} else {
+		g.currentFunction.Append(instruction.Jmp, target)
+	}
+}
+
+// Compares the value on the top of the stack with `value`, and jumps with `jump` when the comparison stands.
+// Returns the offset of the jump so that it can be backpatched.
+func (g *generator) compareAndJump(value int, jump int) int {
+	g.currentFunction.Append(instruction.Dup)
+	g.currentFunction.Append(instruction.Ipush, value)
+	g.currentFunction.Append(instruction.Icmp)
+	g.currentFunction.Append(jump, 0)
+	return g.currentFunction.GetCurrentOffset() - 1
+}
+
+func (g *generator) setJumpTarget(offset, target int) {
+	(*g.currentFunction.GetLines())[offset].SetFirstOperandTo(target)
+}
+
+// Dispatches the value on the top of the stack to the labels. Without enough labels being dense, the value is
+// compared with the labels one by one, otherwise it is done by a binary search on the ranges of the values between the
+// smallest and the largest label. The consecutive labels of the same statements, e.g. `case 1: case 2:`, share a
+// range, and the holes between the labels are the ranges of the default target.
+func (g *generator) dispatchCases(labels []caseLabel, defaultTarget int) {
+	sorted := make([]caseLabel, len(labels))
+	copy(sorted, labels)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].value < sorted[j].value })
+
+	if len(sorted) < minimumCasesOfBinarySearch ||
+		int64(sorted[len(sorted)-1].value)-int64(sorted[0].value)+1 > int64(2*len(sorted)) {
+		for _, label := range labels {
+			jump := g.compareAndJump(label.value, instruction.Jne)
+			g.popAndJumpTo(label.offset)
+			g.setJumpTarget(jump, g.currentFunction.GetCurrentOffset())
+		}
+		g.popAndJumpTo(defaultTarget)
+		return
+	}
+
+	ranges := []caseRange{}
+	for _, label := range sorted {
+		if len(ranges) > 0 && ranges[len(ranges)-1].to+1 < label.value {
+			ranges = append(ranges, caseRange{ranges[len(ranges)-1].to + 1, label.value - 1, defaultTarget, true})
+		}
+		if last := len(ranges) - 1; last >= 0 && !ranges[last].isDefault && ranges[last].target == label.offset &&
+			ranges[last].to+1 == label.value {
+			ranges[last].to = label.value
+			continue
+		}
+		ranges = append(ranges, caseRange{label.value, label.value, label.offset, false})
+	}
+
+	// The values out of the ranges go to the default target
+	jumpsToDefault := []int{
+		g.compareAndJump(sorted[0].value, instruction.Jl),
+		g.compareAndJump(sorted[len(sorted)-1].value, instruction.Jg),
+	}
+	jumpsToDefault = append(jumpsToDefault, g.searchCaseRanges(ranges)...)
+	for _, jump := range jumpsToDefault {
+		g.setJumpTarget(jump, g.currentFunction.GetCurrentOffset())
+	}
+	g.popAndJumpTo(defaultTarget)
+}
+
+// Emits a binary search on the ranges, which cover all the possible values on the top of the stack. Returns the
+// jumps which should be pointed to the default target.
+func (g *generator) searchCaseRanges(ranges []caseRange) (jumpsToDefault []int) {
+	if len(ranges) == 1 {
+		if ranges[0].isDefault {
+			g.currentFunction.Append(instruction.Jmp, 0)
+			return []int{g.currentFunction.GetCurrentOffset() - 1}
+		}
+		g.popAndJumpTo(ranges[0].target)
+		return nil
+	}
+	middle := (len(ranges) - 1) / 2
+	jumpToTheRight := g.compareAndJump(ranges[middle].to, instruction.Jg)
+	jumpsToDefault = g.searchCaseRanges(ranges[:middle+1])
+	g.setJumpTarget(jumpToTheRight, g.currentFunction.GetCurrentOffset())
+	return append(jumpsToDefault, g.searchCaseRanges(ranges[middle+1:])...)
+}
@@ -0,0 +1,32 @@
+package common
+
+// Levenshtein distance between two strings.
+func EditDistance(lhs, rhs string) int {
+	previous := make([]int, len(rhs)+1)
+	current := make([]int, len(rhs)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(lhs); i++ {
+		current[0] = i
+		for j := 1; j <= len(rhs); j++ {
+			cost := 1
+			if lhs[i-1] == rhs[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rhs)]
+}
+
+func min(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
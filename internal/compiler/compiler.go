@@ -2,69 +2,110 @@ package compiler
 
 import (
 	"bufio"
+	"bytes"
+	"c0_compiler/internal/cc0_error"
 	"c0_compiler/internal/instruction"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
+type Error = cc0_error.Error
+
 const lower32BitsMask = 0xffffffff
 
 // Constants
 var magics = []byte{0x43, 0x30, 0x3a, 0x29}
 var version = []byte{0x0, 0x0, 0x0, 0x1}
-var functionMatcher, _ = regexp.Compile("\\.F[0-9]+:")
-var constantParser, _ = regexp.Compile("(?s)^([0-9]+) (.) (.+)")
-
-// Global variables
-var allLines []string
-var paramLengths = []int{}
-var currentLine = 0
-var currentFn = 0
-var w *bufio.Writer
-
-func nextLine() (res *string) {
-	if !hasNextLine() {
+var functionMatcher, _ = regexp.Compile("^\\.F([0-9]+):$")
+var labelMatcher, _ = regexp.Compile("^[A-Za-z_][A-Za-z0-9_]*:$")
+var sectionOrder = []string{".constants:", ".start:", ".functions:"}
+
+// The state of assembling a single text assembly.
+type compiler struct {
+	allLines       []string
+	briefings      []functionBriefing
+	currentLine    int
+	currentFn      int
+	currentSection int
+	errors         []*Error
+	w              *bytes.Buffer
+}
+
+func (c *compiler) report(n int, field string, err *Error) {
+	column := strings.Index(c.allLines[n], field) + 1
+	if column < 1 {
+		column = 1
+	}
+	c.errors = append(c.errors, err.On(n+1, column).Until(n+1, column+len(field)))
+}
+
+// Removes the comment, which starts with a '#' outside of any string constant, and the surrounding spaces.
+func contentOf(line string) string {
+	isInAString := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if isInAString {
+				i++
+			}
+		case '"':
+			isInAString = !isInAString
+		case '#':
+			if !isInAString {
+				return strings.TrimSpace(line[:i])
+			}
+		}
+	}
+	return strings.TrimSpace(line)
+}
+
+func (c *compiler) nextLine() (res *string) {
+	if !c.hasNextLine() {
 		return nil
 	}
-	res = &allLines[currentLine]
-	currentLine++
+	res = &c.allLines[c.currentLine]
+	c.currentLine++
 	return
 }
 
-func collectSection() (int, int) {
-	start, end := currentLine, currentLine
-	for hasNextLine() && !nextLineIsEmpty() && !nextLineIsADelimiter() {
-		end++
-		nextLine()
+// Returns the indices of all the non-empty lines until the next delimiter.
+func (c *compiler) collectSection() []int {
+	indices := []int{}
+	for c.hasNextLine() && !c.nextLineIsADelimiter() {
+		if !c.nextLineIsEmpty() {
+			indices = append(indices, c.currentLine)
+		}
+		c.nextLine()
 	}
-	return start, end
+	return indices
 }
 
-func peekNextLine() (res *string) {
-	if !hasNextLine() {
+func (c *compiler) peekNextLine() (res *string) {
+	if !c.hasNextLine() {
 		return nil
 	}
-	return &allLines[currentLine]
+	return &c.allLines[c.currentLine]
 }
 
-func hasNextLine() bool {
-	return currentLine < len(allLines)
+func (c *compiler) hasNextLine() bool {
+	return c.currentLine < len(c.allLines)
 }
 
-func nextLineIsEmpty() bool {
-	return len(strings.TrimSpace(allLines[currentLine])) == 0
+func (c *compiler) nextLineIsEmpty() bool {
+	return len(contentOf(c.allLines[c.currentLine])) == 0
 }
 
-func nextLineIsADelimiter() bool {
-	line := strings.TrimSpace(allLines[currentLine])
-	if nextLineIsEmpty() {
+func (c *compiler) nextLineIsADelimiter() bool {
+	line := contentOf(c.allLines[c.currentLine])
+	if len(line) == 0 {
 		return false
 	}
 	return line[0] == '.'
 }
 
-func writeI32WithWidth(value, width int) {
+func (c *compiler) writeI32WithWidth(value, width int) {
 	padded := []byte{}
 	for i := 0; i < width; i++ {
 		padded = append(padded, 0)
@@ -73,119 +114,308 @@ func writeI32WithWidth(value, width int) {
 		padded[i] = byte(value & 0xff)
 		value >>= 8
 	}
-	_, _ = w.Write(padded)
+	_, _ = c.w.Write(padded)
+}
+
+// Accepts decimal and hexadecimal integers.
+func parseInteger(field string) (int64, bool) {
+	var value int64
+	var err error
+	unsigned := strings.TrimPrefix(field, "-")
+	if strings.HasPrefix(unsigned, "0x") || strings.HasPrefix(unsigned, "0X") {
+		value, err = strconv.ParseInt(unsigned[2:], 16, 64)
+		if field[0] == '-' {
+			value = -value
+		}
+	} else {
+		value, err = strconv.ParseInt(field, 10, 64)
+	}
+	return value, err == nil
+}
+
+// Both signed and unsigned interpretations of an operand are accepted.
+func fitsInWidth(value int64, width int) bool {
+	bits := uint(8 * width)
+	return value >= -(1<<(bits-1)) && value < 1<<bits
 }
 
-func writeConstant(line string) {
-	matches := constantParser.FindStringSubmatch(line)
-	kind, value := matches[2], matches[3]
-	value = strings.TrimRight(value, "\n")
+func (c *compiler) writeConstant(n, expectedIndex int) {
+	line := contentOf(c.allLines[n])
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		c.report(n, line, cc0_error.Of(cc0_error.MalformedConstant).WithMessage(
+			"A constant must be in the format of `<index> <type> <value>`."))
+		return
+	}
+	if index, ok := parseInteger(fields[0]); !ok || int(index) != expectedIndex {
+		c.report(n, fields[0], cc0_error.Of(cc0_error.MalformedConstant).WithMessage(
+			"Expected the index of the constant to be %d, got `%s`.", expectedIndex, fields[0]))
+	}
+	kind := fields[1]
+	value := strings.TrimSpace(line[strings.Index(line, kind)+len(kind):])
 	if kind == "I" {
-		literal, _ := strconv.Atoi(value)
-		writeI32WithWidth(1, 1)
-		writeI32WithWidth(literal, 4)
+		literal, ok := parseInteger(value)
+		if !ok || literal < math.MinInt32 || literal > math.MaxInt32 {
+			c.report(n, value, cc0_error.Of(cc0_error.MalformedConstant).WithMessage(
+				"`%s` is not a 32-bit integer.", value))
+			return
+		}
+		c.writeI32WithWidth(1, 1)
+		c.writeI32WithWidth(int(literal), 4)
 	} else if kind == "D" {
-		writeI32WithWidth(2, 1)
-		for i := 2; i < len(value); i += 2 {
-			substr := value[i : i+2]
-			parsed, _ := strconv.ParseInt(substr, 16, 16)
-			writeI32WithWidth(int(parsed), 1)
+		var bits uint64
+		if strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0X") {
+			parsed, err := strconv.ParseUint(value[2:], 16, 64)
+			if err != nil || len(value) != 18 {
+				c.report(n, value, cc0_error.Of(cc0_error.MalformedConstant).WithMessage(
+					"`%s` is not a double in the format of 0x followed by 16 hexadecimal digits.", value))
+				return
+			}
+			bits = parsed
+		} else {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				c.report(n, value, cc0_error.Of(cc0_error.MalformedConstant).WithMessage(
+					"`%s` is not a double.", value))
+				return
+			}
+			bits = math.Float64bits(parsed)
 		}
+		c.writeI32WithWidth(2, 1)
+		c.writeI32WithWidth(int(bits>>32), 4)
+		c.writeI32WithWidth(int(bits&lower32BitsMask), 4)
 	} else if kind == "S" {
-		writeI32WithWidth(0, 1)
-		writeI32WithWidth(len(value)-2, 2)
-		for i := 1; i < len(value)-1; i++ {
-			_ = w.WriteByte(value[i])
+		unquoted, err := instruction.UnquoteString(value)
+		if err != nil {
+			c.report(n, value, cc0_error.Of(cc0_error.MalformedConstant).WithMessage(
+				"The string constant is malformed: %s.", err))
+			return
+		}
+		if len(unquoted) > math.MaxUint16 {
+			c.report(n, value, cc0_error.Of(cc0_error.MalformedConstant).WithMessage("The string constant is too long."))
+			return
 		}
+		c.writeI32WithWidth(0, 1)
+		c.writeI32WithWidth(len(unquoted), 2)
+		_, _ = c.w.WriteString(unquoted)
+	} else {
+		c.report(n, kind, cc0_error.Of(cc0_error.MalformedConstant).WithMessage(
+			"Unknown constant type `%s`, expected one of I, D and S.", kind))
 	}
 }
 
-func compileConstants() {
-	start, end := collectSection()
-	writeI32WithWidth(end-start, 2)
-	for i := start; i < end; i++ {
-		line := allLines[i]
-		writeConstant(line)
+func (c *compiler) compileConstants() {
+	indices := c.collectSection()
+	c.writeI32WithWidth(len(indices), 2)
+	for expectedIndex, n := range indices {
+		c.writeConstant(n, expectedIndex)
 	}
 }
 
-func compileInstruction(n int) {
-	line := strings.TrimSpace(allLines[n])
-	fields := strings.Split(line, " ")
-	currentInstruction := instruction.GetCodeFrom(fields[0])
-	writeI32WithWidth(currentInstruction.Code, 1)
-	for i := 1; i < len(fields); i++ {
-		v, _ := strconv.Atoi(fields[i])
-		writeI32WithWidth(v, currentInstruction.Operands[i-1])
+func (c *compiler) compileInstruction(n int, labels map[string]int) {
+	fields := strings.Fields(contentOf(c.allLines[n]))
+	currentInstruction := instruction.GetCodeFrom(strings.ToLower(fields[0]))
+	if currentInstruction == nil {
+		c.report(n, fields[0], cc0_error.Of(cc0_error.UnknownInstruction).WithMessage(
+			"Unknown instruction `%s`.", fields[0]))
+		return
+	}
+	operands := make([]int, 0, len(fields)-1)
+	for _, field := range fields[1:] {
+		if target, ok := labels[field]; ok && instruction.IsAJump(currentInstruction.Code) {
+			operands = append(operands, target)
+			continue
+		}
+		value, ok := parseInteger(field)
+		if !ok {
+			if instruction.IsAJump(currentInstruction.Code) {
+				c.report(n, field, cc0_error.Of(cc0_error.UndefinedLabel).WithMessage(
+					"`%s` is neither an integer nor a label defined in this section.", field))
+			} else {
+				c.report(n, field, cc0_error.Of(cc0_error.MalformedOperand).WithMessage(
+					"The operand `%s` is not an integer.", field))
+			}
+			return
+		}
+		if len(operands) < len(currentInstruction.Operands) {
+			if width := currentInstruction.Operands[len(operands)]; !fitsInWidth(value, width) {
+				c.report(n, field, cc0_error.Of(cc0_error.OperandOutOfRange).WithMessage(
+					"The operand %s of `%s` does not fit in %d byte(s).", field, currentInstruction.Representation, width))
+				return
+			}
+		}
+		operands = append(operands, int(value))
+	}
+	if !currentInstruction.IsValidInstruction(operands...) {
+		c.report(n, fields[0], cc0_error.Of(cc0_error.WrongOperandCount).WithMessage(
+			"`%s` takes %d operand(s), got %d.", currentInstruction.Representation,
+			len(currentInstruction.Operands), len(operands)))
+		return
+	}
+	c.writeI32WithWidth(currentInstruction.Code, 1)
+	for i, operand := range operands {
+		c.writeI32WithWidth(operand, currentInstruction.Operands[i])
 	}
 }
 
-func compileGlobalStartSection() {
-	start, end := collectSection()
-	writeI32WithWidth(end-start, 2)
-	for i := start; i < end; i++ {
-		compileInstruction(i)
+// Labels are lines in the format of `<name>:`, they refer to the index of the instruction right after them and are
+// only visible in the section they are declared in.
+func (c *compiler) compileInstructions() {
+	indices := c.collectSection()
+	labels := map[string]int{}
+	instructions := []int{}
+	for _, n := range indices {
+		line := contentOf(c.allLines[n])
+		if labelMatcher.MatchString(line) {
+			name := line[:len(line)-1]
+			if _, ok := labels[name]; ok {
+				c.report(n, name, cc0_error.Of(cc0_error.RedeclaredAnIdentifier).WithMessage(
+					"The label `%s` is already declared in this section.", name))
+			}
+			labels[name] = len(instructions)
+			continue
+		}
+		instructions = append(instructions, n)
+	}
+	c.writeI32WithWidth(len(instructions), 2)
+	for _, n := range instructions {
+		c.compileInstruction(n, labels)
 	}
 }
 
-func compileFunctionBriefings() {
-	start, end := collectSection()
-	writeI32WithWidth(end-start, 2)
-	for i := start; i < end; i++ {
-		line := allLines[i]
-		fields := strings.Split(line, " ")
-		nParams, _ := strconv.Atoi(fields[2])
-		paramLengths = append(paramLengths, nParams)
-	}
+func (c *compiler) compileGlobalStartSection() {
+	c.compileInstructions()
 }
 
-func compileFunction() {
-	start, end := collectSection()
-	writeI32WithWidth(currentFn, 2) // nameIndex
-	writeI32WithWidth(paramLengths[currentFn], 2)
-	writeI32WithWidth(1, 2)         // level
-	writeI32WithWidth(end-start, 2) // nInstructions
-	for i := start; i < end; i++ {
-		compileInstruction(i)
-	}
-	currentFn++
+// <index> <name-index> <params-size> <level>
+type functionBriefing struct {
+	nameIndex  int
+	paramsSize int
+	level      int
 }
 
-func Run(lines *[]string, destination *bufio.Writer) {
-	w = destination
-	if _, err := w.Write(magics); err != nil {
-		panic(err)
-	}
-	if _, err := w.Write(version); err != nil {
-		panic(err)
+func (c *compiler) compileFunctionBriefings() {
+	indices := c.collectSection()
+	c.writeI32WithWidth(len(indices), 2)
+	for expectedIndex, n := range indices {
+		fields := strings.Fields(contentOf(c.allLines[n]))
+		if len(fields) != 4 {
+			c.report(n, c.allLines[n], cc0_error.Of(cc0_error.MalformedFunctionBriefing).WithMessage(
+				"A function must be in the format of `<index> <name-index> <params-size> <level>`."))
+			continue
+		}
+		values := make([]int, len(fields))
+		for i, field := range fields {
+			value, ok := parseInteger(field)
+			if !ok || !fitsInWidth(value, 2) || value < 0 {
+				c.report(n, field, cc0_error.Of(cc0_error.MalformedFunctionBriefing).WithMessage(
+					"`%s` is not an unsigned 16-bit integer.", field))
+			}
+			values[i] = int(value)
+		}
+		if values[0] != expectedIndex {
+			c.report(n, fields[0], cc0_error.Of(cc0_error.MalformedFunctionBriefing).WithMessage(
+				"Expected the index of the function to be %d, got `%s`.", expectedIndex, fields[0]))
+		}
+		c.briefings = append(c.briefings, functionBriefing{values[1], values[2], values[3]})
 	}
+}
+
+func (c *compiler) compileFunction() {
+	briefing := c.briefings[c.currentFn]
+	c.writeI32WithWidth(briefing.nameIndex, 2)
+	c.writeI32WithWidth(briefing.paramsSize, 2)
+	c.writeI32WithWidth(briefing.level, 2)
+	c.compileInstructions()
+	c.currentFn++
+}
 
-	allLines = *lines
+func (c *compiler) reportMisplacedSection(n int, line string) {
+	c.report(n, line, cc0_error.Of(cc0_error.MisplacedSection).WithMessage(
+		"Unexpected section `%s`, the sections must be in the order of %s, .F0: ... .F%d:.", line,
+		strings.Join(sectionOrder, " "), len(c.briefings)-1))
+}
+
+// Assembles the text assembly into the binary format. Nothing will be written if any error is found.
+func Run(lines *[]string, destination *bufio.Writer) []*Error {
+	c := &compiler{
+		allLines:  strings.Split(strings.Join(*lines, ""), "\n"),
+		briefings: []functionBriefing{},
+		errors:    []*Error{},
+		w:         &bytes.Buffer{},
+	}
+	_, _ = c.w.Write(magics)
+	_, _ = c.w.Write(version)
 
-	for hasNextLine() {
+	for c.hasNextLine() {
 		var line string
-		if nextLineIsEmpty() {
-			nextLine()
+		if c.nextLineIsEmpty() {
+			c.nextLine()
 			continue
 		} else {
-			line = strings.TrimSpace(*peekNextLine())
-		}
-		if line[0] != '.' {
-			panic("Unparsed line: " + line)
-		} else if line == ".constants:" {
-			nextLine()
-			compileConstants()
-		} else if line == ".start:" {
-			nextLine()
-			compileGlobalStartSection()
-		} else if line == ".functions:" {
-			nextLine()
-			compileFunctionBriefings()
-		} else if functionMatcher.MatchString(line) {
-			nextLine()
-			compileFunction()
+			line = contentOf(*c.peekNextLine())
+		}
+		n := c.currentLine
+		c.nextLine()
+		if c.currentSection < len(sectionOrder) && line == sectionOrder[c.currentSection] {
+			switch c.currentSection {
+			case 0:
+				c.compileConstants()
+			case 1:
+				c.compileGlobalStartSection()
+			case 2:
+				c.compileFunctionBriefings()
+			}
+			c.currentSection++
+		} else if matches := functionMatcher.FindStringSubmatch(line); matches != nil {
+			index, _ := strconv.Atoi(matches[1])
+			if c.currentSection < len(sectionOrder) || index != c.currentFn || c.currentFn >= len(c.briefings) {
+				c.reportMisplacedSection(n, line)
+				c.collectSection()
+				continue
+			}
+			c.compileFunction()
+		} else if line[0] == '.' {
+			c.reportMisplacedSection(n, line)
+			c.collectSection()
 		} else {
-			panic("Unparsed line: " + line)
+			c.report(n, line, cc0_error.Of(cc0_error.UnexpectedLine))
+			c.collectSection()
 		}
 	}
+
+	lastLine := len(c.allLines) - 1
+	for lastLine > 0 && len(contentOf(c.allLines[lastLine])) == 0 {
+		lastLine--
+	}
+	if c.currentSection < len(sectionOrder) {
+		c.report(lastLine, "", cc0_error.Of(cc0_error.MisplacedSection).WithMessage(
+			"The section `%s` is missing.", sectionOrder[c.currentSection]))
+	} else if c.currentFn < len(c.briefings) {
+		c.report(lastLine, "", cc0_error.Of(cc0_error.MisplacedSection).WithMessage(
+			"The body of the function %d (`.F%d:`) is missing.", c.currentFn, c.currentFn))
+	}
+
+	if len(c.errors) > 0 {
+		return c.errors
+	}
+	if _, err := c.w.WriteTo(destination); err != nil {
+		panic(err)
+	}
+	return nil
+}
+
+// Assembles the text produced by `assembler.Run`, which can only fail because of a bug in the compiler. The positions
+// of the errors are in the generated text rather than in the source, so the first error is returned as a bug without
+// a position, along with the line it is about.
+func RunOnGeneratedAssembly(lines *[]string, destination *bufio.Writer) *Error {
+	errs := Run(lines, destination)
+	if len(errs) == 0 {
+		return nil
+	}
+	allLines := strings.Split(strings.Join(*lines, ""), "\n")
+	return cc0_error.Of(cc0_error.Bug).WithMessage(
+		"The generated assembly cannot be assembled, which is a bug in the compiler: %s", errs[0].Error()).
+		WithNote(cc0_error.Note("The line %d of the assembly is `%s`.", errs[0].Line(),
+			strings.TrimSpace(allLines[errs[0].Line()-1])))
 }
@@ -0,0 +1,51 @@
+package disassembler
+
+import (
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/object"
+	"fmt"
+	"math"
+)
+
+// Turns a decoded binary back into the text assembly, in the same layout as the one `assembler.Run` produces.
+func Run(file *object.File) *[]string {
+	lines := &[]string{}
+	appendLine := func(format string, params ...interface{}) {
+		*lines = append(*lines, fmt.Sprintf(format, params...))
+	}
+	appendInstructions := func(instructions []instruction.Line) {
+		for _, line := range instructions {
+			appendLine("%s\n", line.String())
+		}
+	}
+
+	appendLine(".constants:\n")
+	for index, c := range file.Constants {
+		switch c.Kind {
+		case instruction.ConstantKindInt:
+			appendLine("%d I %d\n", index, c.Value)
+		case instruction.ConstantKindDouble:
+			appendLine("%d D 0x%016x\n", index, math.Float64bits(c.Value.(float64)))
+		case instruction.ConstantKindString:
+			appendLine("%d S %s\n", index, instruction.QuoteString(c.Value.(string)))
+		}
+	}
+	appendLine("\n")
+
+	appendLine(".start:\n")
+	appendInstructions(file.Start)
+	appendLine("\n")
+
+	appendLine(".functions:\n")
+	for index := range file.Functions {
+		fn := &file.Functions[index]
+		appendLine("%d %d %d %d\t# %s\n", index, fn.NameIndex, fn.ParamsSize, fn.Level, file.NameOf(fn))
+	}
+
+	for index := range file.Functions {
+		fn := &file.Functions[index]
+		appendLine("\n.F%d:\t# %s\n", index, file.NameOf(fn))
+		appendInstructions(fn.Lines)
+	}
+	return lines
+}
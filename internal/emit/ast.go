@@ -0,0 +1,149 @@
+package emit
+
+import (
+	"c0_compiler/internal/ast"
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/token"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Writes the tree with the symbols and the types that the checking has filled. Each node is given with its kind and
+// span, followed by its fields; the positions inside a node are left out since the spans of its children tell them.
+func AST(w io.Writer, program *ast.Program, asJSON bool) error {
+	tree := nodeOf(program)
+	if asJSON {
+		return writeJSON(w, tree)
+	}
+	var sb strings.Builder
+	writeNode(&sb, tree, "")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func positionOf(p ast.Position) string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+func nodeOf(node ast.Node) *object {
+	o := newObject()
+	v := reflect.ValueOf(node).Elem()
+	o.set("node", v.Type().Name())
+	o.set("pos", positionOf(node.Pos()))
+	o.set("end", positionOf(node.End()))
+	for index := 0; index < v.NumField(); index++ {
+		field := v.Type().Field(index)
+		if typed, ok := v.Field(index).Interface().(ast.Typed); ok && field.Anonymous {
+			// The names in the declarations aren't typed
+			if typed.T.IsValid() {
+				o.set("type", typed.T.String())
+			}
+			continue
+		}
+		if value := fieldOf(field.Name, v.Field(index)); value != nil {
+			o.set(lowerFirst(field.Name), value)
+		}
+	}
+	return o
+}
+
+// The value of a field, or nil if the field is left out.
+func fieldOf(name string, v reflect.Value) interface{} {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil
+	}
+	switch value := v.Interface().(type) {
+	case ast.Position:
+		return nil
+	case ast.Type:
+		return value.String()
+	case *instruction.Symbol:
+		return symbolOf(name, value)
+	case *instruction.StructType:
+		return fmt.Sprintf("struct %s of %d slot(s)", value.Name, value.Size)
+	case *instruction.SymbolTable:
+		// The scopes are seen from the symbols of the names
+		return nil
+	case ast.Node:
+		return nodeOf(value)
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return nil
+		}
+		elements := []interface{}{}
+		for index := 0; index < v.Len(); index++ {
+			elements = append(elements, fieldOf(name, v.Index(index)))
+		}
+		return elements
+	case reflect.Bool:
+		if !v.Bool() {
+			return nil
+		}
+	case reflect.Int:
+		// The kinds of the type specifiers and the branches, and the operators, are kept as the kinds of their tokens
+		if name == "Kind" || name == "Op" {
+			return token.KindName(int(v.Int()))
+		}
+	case reflect.Int32:
+		return string(rune(v.Int()))
+	}
+	return v.Interface()
+}
+
+func symbolOf(name string, sb *instruction.Symbol) string {
+	switch {
+	case name == "Field":
+		return fmt.Sprintf("member %s at offset %d", sb.Name, sb.Address)
+	case sb.IsCallable:
+		return fmt.Sprintf("function %s", sb.Name)
+	case sb.IsACompileTimeConstant():
+		return fmt.Sprintf("constant %s = %v", sb.Name, sb.Value)
+	}
+	return fmt.Sprintf("%s at slot %d", sb.Name, sb.Address)
+}
+
+func lowerFirst(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// Writes the node as its kind and span, with its fields indented below it.
+func writeNode(sb *strings.Builder, o *object, indent string) {
+	sb.WriteString(fmt.Sprintf("%s %s-%s\n", o.values["node"], o.values["pos"], o.values["end"]))
+	for _, key := range o.keys[3:] {
+		writeField(sb, key, o.values[key], indent+"  ")
+	}
+}
+
+func writeField(sb *strings.Builder, key string, value interface{}, indent string) {
+	switch value := value.(type) {
+	case *object:
+		sb.WriteString(indent + key + ": ")
+		writeNode(sb, value, indent)
+	case []interface{}:
+		sb.WriteString(indent + key + ":\n")
+		for _, element := range value {
+			if node, ok := element.(*object); ok {
+				sb.WriteString(indent + "- ")
+				writeNode(sb, node, indent+"  ")
+			} else {
+				sb.WriteString(fmt.Sprintf("%s- %v\n", indent, element))
+			}
+		}
+	case string:
+		if key == "value" {
+			// A string or a char literal, which may have any character in it
+			value = fmt.Sprintf("%q", value)
+		}
+		sb.WriteString(fmt.Sprintf("%s%s: %s\n", indent, key, value))
+	default:
+		sb.WriteString(fmt.Sprintf("%s%s: %v\n", indent, key, value))
+	}
+}
@@ -0,0 +1,109 @@
+package emit
+
+import (
+	"c0_compiler/internal/cfg"
+	"c0_compiler/internal/instruction"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type jsonGraph struct {
+	Name       string      `json:"name"`
+	ReturnType string      `json:"returnType"`
+	Parameters []string    `json:"parameters"`
+	Blocks     []jsonBlock `json:"blocks"`
+}
+
+type jsonBlock struct {
+	Index         int        `json:"index"`
+	Instructions  []jsonLine `json:"instructions"`
+	Successors    []int      `json:"successors"`
+	ReachesTheEnd bool       `json:"reachesTheEnd,omitempty"`
+}
+
+// Writes the basic blocks of every function and the edges between them, as a Graphviz graph with a cluster for each
+// function, or as JSON. The end of a function is drawn as a node of its own when it can be reached without returning.
+func CFG(w io.Writer, globalSymbolTable *instruction.SymbolTable, asJSON bool) error {
+	records := []jsonGraph{}
+	for _, sb := range functionsOf(globalSymbolTable) {
+		record := jsonGraph{
+			Name:       sb.Name,
+			ReturnType: typeNameOf(sb.Kind),
+			Parameters: *sb.FnInfo.Parameters,
+			Blocks:     []jsonBlock{},
+		}
+		for _, block := range cfg.Build(*sb.FnInfo.GetLines()).Blocks {
+			successors := []int{}
+			for _, successor := range block.Successors {
+				successors = append(successors, successor.Index)
+			}
+			record.Blocks = append(record.Blocks, jsonBlock{
+				Index:         block.Index,
+				Instructions:  linesOf(sb.FnInfo, block.Start, block.End, globalSymbolTable),
+				Successors:    successors,
+				ReachesTheEnd: block.ReachesTheEnd,
+			})
+		}
+		records = append(records, record)
+	}
+	if asJSON {
+		return writeJSON(w, records)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("digraph cfg {\n")
+	sb.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	for index, record := range records {
+		writeGraph(&sb, index, record)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeGraph(sb *strings.Builder, index int, record jsonGraph) {
+	nodeOf := func(block int) string {
+		return fmt.Sprintf("f%d_b%d", index, block)
+	}
+	end := fmt.Sprintf("f%d_end", index)
+
+	sb.WriteString(fmt.Sprintf("  subgraph cluster_f%d {\n", index))
+	sb.WriteString(fmt.Sprintf("    label=\"%s\";\n", escapeLabel(
+		fmt.Sprintf("%s %s(%s)", record.ReturnType, record.Name, strings.Join(record.Parameters, ", ")))))
+	reachesTheEnd := len(record.Blocks) == 0
+	for _, block := range record.Blocks {
+		var label strings.Builder
+		for _, line := range block.Instructions {
+			text := fmt.Sprintf("%d: %s", line.Offset, line.Instruction)
+			for _, operand := range line.Operands {
+				text += fmt.Sprintf(" %d", operand)
+			}
+			if line.Comment != "" {
+				text += "  # " + line.Comment
+			}
+			// Each line is left-justified by the "\l" ending it
+			label.WriteString(escapeLabel(text) + "\\l")
+		}
+		sb.WriteString(fmt.Sprintf("    %s [label=\"%s\"];\n", nodeOf(block.Index), label.String()))
+		reachesTheEnd = reachesTheEnd || block.ReachesTheEnd
+	}
+	if reachesTheEnd {
+		sb.WriteString(fmt.Sprintf("    %s [label=\"end\", shape=oval];\n", end))
+	}
+
+	for _, block := range record.Blocks {
+		for _, successor := range block.Successors {
+			sb.WriteString(fmt.Sprintf("    %s -> %s;\n", nodeOf(block.Index), nodeOf(successor)))
+		}
+		if block.ReachesTheEnd {
+			sb.WriteString(fmt.Sprintf("    %s -> %s;\n", nodeOf(block.Index), end))
+		}
+	}
+	sb.WriteString("  }\n")
+}
+
+// Escapes the text for a quoted string of the DOT language, in which only '"' and '\' need to be escaped.
+func escapeLabel(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text)
+}
@@ -0,0 +1,161 @@
+package emit
+
+import (
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/token"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// The instructions of the code run before `main`, which initializes the global variables.
+const startName = ".start"
+
+type jsonFunction struct {
+	Name         string     `json:"name"`
+	ReturnType   string     `json:"returnType"`
+	Parameters   []string   `json:"parameters"`
+	Instructions []jsonLine `json:"instructions"`
+}
+
+type jsonLine struct {
+	Offset      int    `json:"offset"`
+	Instruction string `json:"instruction"`
+	Operands    []int  `json:"operands"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+// Writes the instructions of every function before they are assembled, along with what their operands refer to:
+// the variable that `loada` loads, the function that `call` calls and the literal that `loadc` loads.
+func IR(w io.Writer, globalSymbolTable *instruction.SymbolTable, asJSON bool) error {
+	records := []jsonFunction{}
+	for _, sb := range functionsOf(globalSymbolTable) {
+		records = append(records, jsonFunction{
+			Name:         sb.Name,
+			ReturnType:   typeNameOf(sb.Kind),
+			Parameters:   *sb.FnInfo.Parameters,
+			Instructions: linesOf(sb.FnInfo, 0, len(*sb.FnInfo.GetLines()), globalSymbolTable),
+		})
+	}
+	if asJSON {
+		return writeJSON(w, records)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for index, record := range records {
+		if index > 0 {
+			_, _ = fmt.Fprintln(tw)
+		}
+		_, _ = fmt.Fprintf(tw, "%s %s(%s):\n", record.ReturnType, record.Name, strings.Join(record.Parameters, ", "))
+		for _, line := range record.Instructions {
+			_, _ = fmt.Fprintf(tw, "%d\t%s", line.Offset, line.Instruction)
+			for _, operand := range line.Operands {
+				_, _ = fmt.Fprintf(tw, " %d", operand)
+			}
+			if line.Comment != "" {
+				_, _ = fmt.Fprintf(tw, "\t# %s", line.Comment)
+			}
+			_, _ = fmt.Fprintln(tw)
+		}
+	}
+	return tw.Flush()
+}
+
+// The code initializing the global variables, followed by the functions in the order of their definitions.
+func functionsOf(globalSymbolTable *instruction.SymbolTable) []*instruction.Symbol {
+	functions := []*instruction.Symbol{{Name: startName, Kind: token.Void, FnInfo: globalSymbolTable.RelatedFunction}}
+	for _, sb := range globalSymbolTable.Symbols {
+		if sb.IsCallable {
+			functions = append(functions, sb)
+		}
+	}
+	sort.SliceStable(functions[1:], func(i, j int) bool { return functions[i+1].Address < functions[j+1].Address })
+	return functions
+}
+
+// The lines [start, end) of the function.
+func linesOf(fn *instruction.Fn, start, end int, globalSymbolTable *instruction.SymbolTable) []jsonLine {
+	records := []jsonLine{}
+	lines := *fn.GetLines()
+	for offset := start; offset < end; offset++ {
+		records = append(records, jsonLine{
+			Offset:      offset,
+			Instruction: lines[offset].I.Representation,
+			Operands:    *lines[offset].Operands,
+			Comment:     commentOf(lines[offset], fn.RelatedSymbolTable, globalSymbolTable),
+		})
+	}
+	return records
+}
+
+func typeNameOf(kind int) string {
+	switch kind {
+	case token.Void:
+		return "void"
+	case token.Char:
+		return "char"
+	case token.Int:
+		return "int"
+	case token.Double:
+		return "double"
+	}
+	return "<invalid>"
+}
+
+func commentOf(line instruction.Line, table, globalSymbolTable *instruction.SymbolTable) string {
+	operands := *line.Operands
+	switch line.I.Code {
+	case instruction.Loada:
+		if operands[0] > 0 {
+			table = globalSymbolTable
+		}
+		return variableAt(table, operands[1])
+	case instruction.Call:
+		for _, sb := range globalSymbolTable.Symbols {
+			if sb.IsCallable && sb.Address == operands[0] {
+				return sb.Name
+			}
+		}
+	case instruction.Loadc:
+		for _, c := range *globalSymbolTable.Constants {
+			if c.Address != operands[0] {
+				continue
+			}
+			if c.Kind == instruction.ConstantKindString {
+				return fmt.Sprintf("%q", c.Value)
+			}
+			return fmt.Sprint(c.Value)
+		}
+	}
+	return ""
+}
+
+// The name of the variable, or of the member of a struct, taking the slot at `address`, e.g. "p.from.x".
+func variableAt(table *instruction.SymbolTable, address int) string {
+	for _, sb := range table.Symbols {
+		if sb.IsCallable || sb.IsACompileTimeConstant() || address < sb.Address || address >= sb.Address+sb.Size() {
+			continue
+		}
+		name, offset := sb.Name, address-sb.Address
+		for structType := sb.Struct; structType != nil && !sb.IsAnArray(); {
+			member := memberAt(structType, offset)
+			if member == nil {
+				break
+			}
+			name, offset, structType = name+"."+member.Name, offset-member.Address, member.Struct
+		}
+		return name
+	}
+	return ""
+}
+
+func memberAt(structType *instruction.StructType, offset int) *instruction.Symbol {
+	for _, member := range structType.Members {
+		if offset >= member.Address && offset < member.Address+member.Size() {
+			return member
+		}
+	}
+	return nil
+}
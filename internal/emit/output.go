@@ -0,0 +1,42 @@
+package emit
+
+import (
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+type jsonAssembly struct {
+	Lines []string `json:"lines"`
+}
+
+type jsonBinary struct {
+	Size int    `json:"size"`
+	Hex  string `json:"hex"`
+}
+
+// Writes the text assembly, the same as what `cc0 -s` outputs.
+func Assembly(w io.Writer, lines []string, asJSON bool) error {
+	if asJSON {
+		record := jsonAssembly{Lines: []string{}}
+		for _, line := range lines {
+			record.Lines = append(record.Lines, strings.TrimSuffix(line, "\n"))
+		}
+		return writeJSON(w, record)
+	}
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes the binary, the same as what `cc0 -c` outputs, which is given in hex in JSON.
+func Binary(w io.Writer, binary []byte, asJSON bool) error {
+	if asJSON {
+		return writeJSON(w, jsonBinary{Size: len(binary), Hex: hex.EncodeToString(binary)})
+	}
+	_, err := w.Write(binary)
+	return err
+}
@@ -0,0 +1,51 @@
+package emit
+
+import (
+	"c0_compiler/internal/token"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+type jsonToken struct {
+	Kind      string      `json:"kind"`
+	Value     interface{} `json:"value,omitempty"`
+	Line      int         `json:"line"`
+	Column    int         `json:"column"`
+	EndColumn int         `json:"endColumn"`
+}
+
+// The value of an identifier or a literal, or nil for the other tokens. A char is given as a string.
+func valueOf(t *token.Token) interface{} {
+	if t.Kind == token.CharLiteral {
+		return string(t.Value.(int32))
+	}
+	return t.Value
+}
+
+// Writes the tokens one per line, as `line:column kind value`.
+func Tokens(w io.Writer, tokens []token.Token, asJSON bool) error {
+	if asJSON {
+		records := []jsonToken{}
+		for index := range tokens {
+			t := &tokens[index]
+			records = append(records, jsonToken{token.KindName(t.Kind), valueOf(t), t.Line, t.Column, t.EndColumn})
+		}
+		return writeJSON(w, records)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for index := range tokens {
+		t := &tokens[index]
+		_, _ = fmt.Fprintf(tw, "%d:%d\t%s", t.Line, t.Column, token.KindName(t.Kind))
+		switch value := valueOf(t).(type) {
+		case nil:
+		case string:
+			_, _ = fmt.Fprintf(tw, "\t%q", value)
+		default:
+			_, _ = fmt.Fprintf(tw, "\t%v", value)
+		}
+		_, _ = fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
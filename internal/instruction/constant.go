@@ -1,5 +1,11 @@
 package instruction
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	ConstantKindInt = iota
 	ConstantKindDouble
@@ -11,3 +17,74 @@ type Constant struct {
 	Value   interface{}
 	Address int
 }
+
+// Quotes a string constant for the text assembly. Every byte that is not printable is escaped so that the text
+// can be turned back into exactly the same bytes by `UnquoteString`.
+func QuoteString(value string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch b := value[i]; b {
+		case '"', '\\':
+			builder.WriteByte('\\')
+			builder.WriteByte(b)
+		case '\n':
+			builder.WriteString("\\n")
+		case '\r':
+			builder.WriteString("\\r")
+		case '\t':
+			builder.WriteString("\\t")
+		default:
+			if b < 0x20 || b >= 0x7f {
+				builder.WriteString(fmt.Sprintf("\\x%02x", b))
+			} else {
+				builder.WriteByte(b)
+			}
+		}
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+func UnquoteString(quoted string) (string, error) {
+	if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+		return "", fmt.Errorf("string constants must be enclosed in double quotes")
+	}
+	var builder strings.Builder
+	content := quoted[1 : len(quoted)-1]
+	for i := 0; i < len(content); i++ {
+		b := content[i]
+		if b == '"' {
+			return "", fmt.Errorf("unescaped double quote in a string constant")
+		}
+		if b != '\\' {
+			builder.WriteByte(b)
+			continue
+		}
+		i++
+		if i >= len(content) {
+			return "", fmt.Errorf("incomplete escape sequence in a string constant")
+		}
+		switch content[i] {
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 't':
+			builder.WriteByte('\t')
+		case 'x':
+			if i+2 >= len(content) {
+				return "", fmt.Errorf("incomplete escape sequence in a string constant")
+			}
+			parsed, err := strconv.ParseUint(content[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("illegal escape sequence \\x%s in a string constant", content[i+1:i+3])
+			}
+			builder.WriteByte(byte(parsed))
+			i += 2
+		default:
+			builder.WriteByte(content[i])
+		}
+	}
+	return builder.String(), nil
+}
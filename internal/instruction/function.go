@@ -22,6 +22,7 @@ type Fn struct {
 	emptyMemorySlots      *PriorityQueue
 	currentConstantOffset int
 	stackSize             int
+	frameSize             int
 	RelatedSymbolTable    *SymbolTable
 }
 
@@ -45,10 +46,6 @@ func (f *Fn) GetCurrentOffset() int {
 	return len(*f.instructions.lines)
 }
 
-func (f *Fn) ReplaceNopAt(offset int, instruction int, operands ...int) {
-	f.ChangeInstructionTo(offset, instruction, operands...)
-}
-
 func (f *Fn) GetCurrentConstantOffset() int {
 	return f.currentConstantOffset
 }
@@ -73,8 +70,8 @@ func (f *Fn) generateLine(instruction int, operands ...int) Line {
 	i := GetInstruction(instruction)
 	f.stackSize += i.changesToStackSize
 	if !i.IsValidInstruction(operands...) {
-		cc0_error.PrintfToStdErr("Incorrect usage of instruction 0x%x!\n", instruction)
-		cc0_error.ThrowAndExit(cc0_error.Analyzer)
+		// Only a bug in the analyzer can lead here
+		panic(cc0_error.Of(cc0_error.Bug).WithMessage("Incorrect usage of instruction 0x%x!", instruction))
 	}
 	f.instructions.offset += i.offset
 	return Line{I: i, Operands: &operands}
@@ -85,38 +82,70 @@ func (f *Fn) Append(instruction int, operands ...int) {
 }
 
 func (f *Fn) NextMemorySlot(kind int) (slot int) {
+	if kind == token.Double {
+		return f.NextMemorySlots(2)
+	}
+	return f.NextMemorySlots(1)
+}
+
+// Takes the first `size` consecutive empty slots and returns the first one. The empty slots are kept in a min-heap,
+// whose largest one stands for itself and all the slots after it, which have never been taken.
+func (f *Fn) NextMemorySlots(size int) (slot int) {
 	queue := f.emptyMemorySlots
-	slot = queue.Pop().(int)
-	if queue.Len() == 0 {
-		if kind == token.Double {
-			queue.Push(slot + 2)
-		} else {
-			queue.Push(slot + 1)
+	emptySlots := []int{}
+	for queue.Len() > 0 {
+		emptySlots = append(emptySlots, heap.Pop(queue).(int))
+	}
+	first := 0
+	for index := 1; index < len(emptySlots) && index-first < size; index++ {
+		if emptySlots[index] != emptySlots[index-1]+1 {
+			first = index
 		}
-	} else {
-		if kind != token.Double {
-			return
+	}
+	slot = emptySlots[first]
+	taken := size
+	if len(emptySlots)-first <= size {
+		// Takes the never taken slots as well
+		taken = len(emptySlots) - first
+		heap.Push(queue, slot+size)
+		if slot+size > f.frameSize {
+			f.frameSize = slot + size
 		}
-		slotBackup := []int{}
-		for {
-			nextSlot := queue.Pop().(int)
-			if nextSlot-slot >= 2 {
-				for _, backedSlot := range slotBackup {
-					queue.Push(backedSlot)
-				}
-				return
-			}
-			slotBackup = append(slotBackup, slot)
-			slot = nextSlot
+	}
+	for index, emptySlot := range emptySlots {
+		if index < first || index >= first+taken {
+			heap.Push(queue, emptySlot)
 		}
 	}
 	return
 }
 
+// Gives the slots back so that they can be taken again, which is done when the scope of their symbol ends.
+func (f *Fn) ReleaseMemorySlots(slot, size int) {
+	for offset := 0; offset < size; offset++ {
+		heap.Push(f.emptyMemorySlots, slot+offset)
+	}
+}
+
+// The number of the slots which have ever been taken, which are all the function needs for its parameters and
+// variables since the slots are reused.
+func (f *Fn) FrameSize() int {
+	return f.frameSize
+}
+
 func (f *Fn) PopStack(reservedSize int) {
 	f.Append(Popn, f.stackSize-reservedSize)
 }
 
+// Replaces all the lines, which is done by the optimizer.
+func (f *Fn) SetLines(lines []Line) {
+	f.instructions.offset = 0
+	for _, line := range lines {
+		f.instructions.offset += line.I.offset
+	}
+	*f.instructions.lines = lines
+}
+
 func (f *Fn) ChangeInstructionTo(offset, instruction int, operands ...int) {
 	l := &((*f.instructions.lines)[offset])
 	copied := make([]int, len(operands))
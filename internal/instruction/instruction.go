@@ -147,6 +147,16 @@ func GetCodeFrom(representation string) *Instruction {
 	return nil
 }
 
+// Whether the instruction is `jmp` or one of the conditional jumps, whose only operand is the offset of the target.
+func IsAJump(code int) bool {
+	return code >= Jmp && code <= Jle
+}
+
+// Whether the instruction is one of `ret`, `iret`, `dret` and `aret`.
+func IsAReturn(code int) bool {
+	return code >= Ret && code <= Aret
+}
+
 func (instruction Instruction) IsValidInstruction(operands ...int) bool {
 	return len(operands) == instruction.nOperands
 }
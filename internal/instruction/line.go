@@ -1,5 +1,7 @@
 package instruction
 
+import "fmt"
+
 type Line struct {
 	I        Instruction
 	Operands *[]int
@@ -20,3 +22,41 @@ func (l *Line) ChangeInstructionTo(instruction int, operands ...int) {
 	l.I = GetInstruction(instruction)
 	l.Operands = &copied
 }
+
+func (l *Line) String() string {
+	str := l.I.Representation
+	for _, operand := range *l.Operands {
+		str += fmt.Sprintf(" %d", operand)
+	}
+	return str
+}
+
+// Returns the lines which are kept, with the jumps pointed to where their targets are moved. A jump to a removed line
+// goes to the first kept line after it. The lines are returned as they are if all of them are kept.
+func RemoveLines(lines []Line, isKept func(index int) bool) ([]Line, bool) {
+	newOffsets := make([]int, len(lines)+1)
+	count := 0
+	for index := range lines {
+		newOffsets[index] = count
+		if isKept(index) {
+			count++
+		}
+	}
+	newOffsets[len(lines)] = count
+	if count == len(lines) {
+		return lines, false
+	}
+
+	result := make([]Line, 0, count)
+	for index := range lines {
+		if !isKept(index) {
+			continue
+		}
+		line := lines[index]
+		if IsAJump(line.I.Code) {
+			line.SetFirstOperandTo(newOffsets[(*line.Operands)[0]])
+		}
+		result = append(result, line)
+	}
+	return result, true
+}
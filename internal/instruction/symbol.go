@@ -2,6 +2,8 @@ package instruction
 
 import (
 	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/common"
+	"c0_compiler/internal/token"
 )
 
 type Symbol struct {
@@ -11,6 +13,81 @@ type Symbol struct {
 	IsConstant bool
 	Kind       int
 	Name       string
+	Line       int // where the symbol is declared
+	Column     int
+	Dimensions []int        // the lengths of the dimensions if the symbol is an array, whose `Kind` is of the elements
+	Struct     *StructType  // the type of the symbol if its `Kind` is `token.Struct`
+	Value      interface{}  // an int32 or a float64 if the symbol is a constant whose value is known by the compiler
+	Scope      *SymbolTable // the table which the symbol is declared in, nil for the members of the structs
+}
+
+// Whether the symbol is a constant whose value is used in place of it, which takes no slot.
+func (sb *Symbol) IsACompileTimeConstant() bool {
+	return sb.Value != nil
+}
+
+// The function whose frame has the slot of the symbol, which is the one of the global variables for them.
+func (sb *Symbol) Frame() *Fn {
+	return sb.Scope.RelatedFunction
+}
+
+func (sb *Symbol) IsAnArray() bool {
+	return len(sb.Dimensions) > 0
+}
+
+// The number of the slots taken by the symbol.
+func (sb *Symbol) Size() int {
+	switch {
+	case sb.IsAnArray():
+		return 1
+	case sb.Kind == token.Struct:
+		return sb.Struct.Size
+	case sb.Kind == token.Double:
+		return 2
+	}
+	return 1
+}
+
+// The members of a struct are laid out in the slots one after another in the order of their declarations, and the
+// `Address` of a member is its offset in the struct.
+type StructType struct {
+	Name    string
+	Members []*Symbol
+	Size    int
+	Line    int // where the struct is declared
+	Column  int
+}
+
+func (st *StructType) GetMemberNamed(name string) *Symbol {
+	for _, member := range st.Members {
+		if member.Name == name {
+			return member
+		}
+	}
+	return nil
+}
+
+// Returns the member whose name is the most similar to the given one, or "" if none is similar enough.
+func (st *StructType) GetSimilarMemberName(name string) (result string) {
+	bestDistance := (len(name) + 2) / 3
+	for _, member := range st.Members {
+		if distance := common.EditDistance(name, member.Name); distance <= bestDistance &&
+			(distance < bestDistance || result == "" || member.Name < result) {
+			bestDistance, result = distance, member.Name
+		}
+	}
+	return
+}
+
+func (st *StructType) AddAMember(name string, kind int, structType *StructType) *Error {
+	if st.GetMemberNamed(name) != nil {
+		return cc0_error.Of(cc0_error.RedeclaredAnIdentifier)
+	}
+	member := &Symbol{Kind: kind, Name: name, Struct: structType}
+	member.Address = st.Size
+	st.Size += member.Size()
+	st.Members = append(st.Members, member)
+	return nil
 }
 
 type SymbolTable struct {
@@ -19,6 +96,7 @@ type SymbolTable struct {
 	RelatedFunction *Fn
 	Symbols         map[string]*Symbol
 	fnCount         int
+	literalCount    int
 }
 
 func (st SymbolTable) HasDeclared(name string) bool {
@@ -46,6 +124,20 @@ func (st SymbolTable) GetSymbolNamed(name string) *Symbol {
 	}
 }
 
+// Returns the visible name which is the most similar to the given one, or "" if none is similar enough.
+func (st SymbolTable) GetSimilarName(name string) (result string) {
+	bestDistance := (len(name) + 2) / 3
+	for currentTable := &st; currentTable != nil; currentTable = currentTable.Parent {
+		for candidate := range currentTable.Symbols {
+			if distance := common.EditDistance(name, candidate); distance <= bestDistance &&
+				(distance < bestDistance || result == "" || candidate < result) {
+				bestDistance, result = distance, candidate
+			}
+		}
+	}
+	return
+}
+
 func (st SymbolTable) GetAddressOf(symbol string) int {
 	sb := st.GetSymbolNamed(symbol)
 	if sb == nil {
@@ -58,12 +150,12 @@ func (st SymbolTable) RemoveConstantNamed(name string) *Error {
 	if !st.HasDeclared(name) || !st.Symbols[name].IsConstant {
 		return cc0_error.Of(cc0_error.Bug)
 	}
-	st.RelatedFunction.emptyMemorySlots.Push(st.Symbols[name].Address)
+	st.RelatedFunction.ReleaseMemorySlots(st.Symbols[name].Address, st.Symbols[name].Size())
 	delete(st.Symbols, name)
 	return nil
 }
 
-func (st SymbolTable) AddAConstant(name string, kind int) *Error {
+func (st *SymbolTable) AddAConstant(name string, kind int) *Error {
 	if st.HasDeclared(name) {
 		return cc0_error.Of(cc0_error.RedeclaredAnIdentifier)
 	}
@@ -74,11 +166,33 @@ func (st SymbolTable) AddAConstant(name string, kind int) *Error {
 		IsConstant: true,
 		Kind:       kind,
 		Name:       name,
+		Scope:      st,
 	}
 	return nil
 }
 
-func (st SymbolTable) AddAVariable(name string, kind int) *Error {
+// The constant takes no slot until `TakeASlotFor` is called, since its value may turn out to be known by the compiler.
+func (st *SymbolTable) AddAConstantWithoutASlot(name string, kind int) *Error {
+	if st.HasDeclared(name) {
+		return cc0_error.Of(cc0_error.RedeclaredAnIdentifier)
+	}
+	st.Symbols[name] = &Symbol{
+		Address:    -1,
+		FnInfo:     nil,
+		IsCallable: false,
+		IsConstant: true,
+		Kind:       kind,
+		Name:       name,
+		Scope:      st,
+	}
+	return nil
+}
+
+func (st SymbolTable) TakeASlotFor(sb *Symbol) {
+	sb.Address = st.RelatedFunction.NextMemorySlot(sb.Kind)
+}
+
+func (st *SymbolTable) AddAVariable(name string, kind int) *Error {
 	if st.HasDeclared(name) {
 		return cc0_error.Of(cc0_error.RedeclaredAnIdentifier)
 	}
@@ -89,6 +203,43 @@ func (st SymbolTable) AddAVariable(name string, kind int) *Error {
 		IsConstant: false,
 		Kind:       kind,
 		Name:       name,
+		Scope:      st,
+	}
+	return nil
+}
+
+// Only the address of the array is kept in the slot of the symbol, the elements are in the heap.
+func (st *SymbolTable) AddAnArray(name string, kind int, dimensions []int) *Error {
+	if st.HasDeclared(name) {
+		return cc0_error.Of(cc0_error.RedeclaredAnIdentifier)
+	}
+	st.Symbols[name] = &Symbol{
+		Address:    st.RelatedFunction.NextMemorySlot(token.Int),
+		FnInfo:     nil,
+		IsCallable: false,
+		IsConstant: false,
+		Kind:       kind,
+		Name:       name,
+		Scope:      st,
+		Dimensions: dimensions,
+	}
+	return nil
+}
+
+// The members of the struct are kept in the consecutive slots of the symbol.
+func (st *SymbolTable) AddAStruct(name string, structType *StructType) *Error {
+	if st.HasDeclared(name) {
+		return cc0_error.Of(cc0_error.RedeclaredAnIdentifier)
+	}
+	st.Symbols[name] = &Symbol{
+		Address:    st.RelatedFunction.NextMemorySlots(structType.Size),
+		FnInfo:     nil,
+		IsCallable: false,
+		IsConstant: false,
+		Kind:       token.Struct,
+		Name:       name,
+		Scope:      st,
+		Struct:     structType,
 	}
 	return nil
 }
@@ -100,7 +251,10 @@ func InitSymbolTable(parent *SymbolTable, fi *Fn) *SymbolTable {
 		Parent:          parent,
 		RelatedFunction: fi,
 	}
-	fi.RelatedSymbolTable = result
+	// The tables of the blocks in a function are the children of the one of the function
+	if fi.RelatedSymbolTable == nil {
+		fi.RelatedSymbolTable = result
+	}
 	return result
 }
 
@@ -108,6 +262,16 @@ func (st *SymbolTable) AppendChildSymbolTable(relatedFunction *Fn) *SymbolTable
 	return InitSymbolTable(st, relatedFunction)
 }
 
+// Gives the slots of the symbols back to the function, which is done when the block of the table ends. The slots of
+// the arrays are kept, since the elements they point to are allocated once for the whole function.
+func (st SymbolTable) ReleaseMemorySlots() {
+	for _, sb := range st.Symbols {
+		if sb.Address >= 0 && !sb.IsCallable && !sb.IsAnArray() {
+			st.RelatedFunction.ReleaseMemorySlots(sb.Address, sb.Size())
+		}
+	}
+}
+
 // Functions generated by this function would not have addresses. Their addresses should be reassigned by the assembler.
 func (st *SymbolTable) AddAFunction(name string, returnType int, fn *Fn) *Error {
 	if st.HasDeclared(name) {
@@ -120,23 +284,39 @@ func (st *SymbolTable) AddAFunction(name string, returnType int, fn *Fn) *Error
 		IsConstant: true,
 		Kind:       returnType,
 		Name:       name,
+		Scope:      st,
 	}
 	return nil
 }
 
-func (st *SymbolTable) GetLevelDiff(name string) int {
-	if _, ok := st.Symbols[name]; ok {
-		return 0
+// Returns the symbol visible by the name from the table, the level difference of `loada` from the table to it, and its
+// slot in its frame, or nil if there is no such symbol.
+func (st *SymbolTable) Resolve(name string) (sb *Symbol, levelDiff, address int) {
+	if sb = st.GetSymbolNamed(name); sb == nil {
+		return nil, 0, -1
 	}
-	return 1
+	return sb, st.GetLevelDiff(sb), sb.Address
 }
 
-var emptyConstantSlot = 0
+// The number of the frames left from the one of the table to reach the one of the symbol, which is visible from the
+// table. The blocks of a function share its frame, so only the tables of different functions count.
+func (st *SymbolTable) GetLevelDiff(sb *Symbol) (levelDiff int) {
+	for currentTable := st; currentTable != sb.Scope; currentTable = currentTable.Parent {
+		if currentTable.Parent == nil {
+			// Only a bug in the analyzer can lead here
+			panic(cc0_error.Of(cc0_error.Bug).WithMessage("The symbol '%s' is not visible from the table!", sb.Name))
+		}
+		if currentTable.Parent.RelatedFunction != currentTable.RelatedFunction {
+			levelDiff++
+		}
+	}
+	return
+}
 
 // This is almost always only received by the global symbol table.
 func (st *SymbolTable) AddALiteral(kind int, value interface{}) (address int) {
-	address = emptyConstantSlot
-	emptyConstantSlot++
+	address = st.literalCount
+	st.literalCount++
 	*st.Constants = append(*st.Constants, Constant{
 		Kind:    kind,
 		Value:   value,
@@ -0,0 +1,163 @@
+package instruction
+
+import (
+	"c0_compiler/internal/token"
+	"testing"
+)
+
+// The scopes of
+//
+//	int g; double x;
+//	int f(int p) {
+//		int x;
+//		{ double x; int b; { int y; int n(int q) { int x; { int z; } } } }
+//	}
+//
+// where `n` is nested in `f` so that there are three frames.
+type scopes struct {
+	global, function, block, innerBlock, nested, nestedBlock *SymbolTable
+}
+
+func newScopes(t *testing.T) scopes {
+	var s scopes
+	s.global = InitSymbolTable(nil, InitFn(token.Void))
+	s.function = s.global.AppendChildSymbolTable(InitFn(token.Int))
+	s.block = s.function.AppendChildSymbolTable(s.function.RelatedFunction)
+	s.innerBlock = s.block.AppendChildSymbolTable(s.block.RelatedFunction)
+	s.nested = s.innerBlock.AppendChildSymbolTable(InitFn(token.Void))
+	s.nestedBlock = s.nested.AppendChildSymbolTable(s.nested.RelatedFunction)
+
+	for _, err := range []*Error{
+		s.global.AddAVariable("g", token.Int),
+		s.global.AddAVariable("x", token.Double),
+		s.function.AddAVariable("p", token.Int),
+		s.function.AddAVariable("x", token.Int),
+		s.block.AddAVariable("x", token.Double),
+		s.block.AddAVariable("b", token.Int),
+		s.innerBlock.AddAVariable("y", token.Int),
+		s.nested.AddAVariable("q", token.Int),
+		s.nested.AddAVariable("x", token.Int),
+		s.nestedBlock.AddAVariable("z", token.Int),
+	} {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return s
+}
+
+func TestResolve(t *testing.T) {
+	s := newScopes(t)
+	tests := []struct {
+		name      string
+		from      *SymbolTable
+		symbol    string
+		scope     *SymbolTable
+		levelDiff int
+		address   int
+	}{
+		{"global from global", s.global, "g", s.global, 0, 0},
+		{"global from function", s.function, "g", s.global, 1, 0},
+		{"global from block", s.innerBlock, "g", s.global, 1, 0},
+		{"global from nested function", s.nestedBlock, "g", s.global, 2, 0},
+		{"parameter", s.function, "p", s.function, 0, 0},
+		{"parameter from block", s.innerBlock, "p", s.function, 0, 0},
+		{"parameter from nested function", s.nestedBlock, "p", s.function, 1, 0},
+		{"local", s.function, "x", s.function, 0, 1},
+		{"local of block", s.innerBlock, "b", s.block, 0, 4},
+		{"local of inner block", s.innerBlock, "y", s.innerBlock, 0, 5},
+		{"local of outer function", s.nestedBlock, "y", s.innerBlock, 1, 5},
+		{"parameter of nested function", s.nestedBlock, "q", s.nested, 0, 0},
+		{"local of nested block", s.nestedBlock, "z", s.nestedBlock, 0, 2},
+		{"global shadowed by local", s.function, "x", s.function, 0, 1},
+		{"local shadowed by block", s.block, "x", s.block, 0, 2},
+		{"block shadowed by nested function", s.nestedBlock, "x", s.nested, 0, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sb, levelDiff, address := test.from.Resolve(test.symbol)
+			if sb == nil {
+				t.Fatalf("'%s' is not found", test.symbol)
+			}
+			if sb.Scope != test.scope {
+				t.Errorf("'%s' is found in the wrong scope", test.symbol)
+			}
+			if levelDiff != test.levelDiff {
+				t.Errorf("level difference = %d, want %d", levelDiff, test.levelDiff)
+			}
+			if address != test.address {
+				t.Errorf("address = %d, want %d", address, test.address)
+			}
+		})
+	}
+}
+
+func TestResolveUndefined(t *testing.T) {
+	s := newScopes(t)
+	if sb, _, address := s.function.Resolve("z"); sb != nil || address != -1 {
+		t.Errorf("'z' of a nested block is visible from the function")
+	}
+	if sb, _, _ := s.global.Resolve("p"); sb != nil {
+		t.Errorf("the parameter 'p' is visible from the global scope")
+	}
+}
+
+func TestFrame(t *testing.T) {
+	s := newScopes(t)
+	for _, test := range []struct {
+		from   *SymbolTable
+		symbol string
+		frame  *Fn
+	}{
+		{s.innerBlock, "g", s.global.RelatedFunction},
+		{s.innerBlock, "y", s.function.RelatedFunction},
+		{s.nestedBlock, "z", s.nested.RelatedFunction},
+	} {
+		if sb := test.from.GetSymbolNamed(test.symbol); sb.Frame() != test.frame {
+			t.Errorf("'%s' is in the wrong frame", test.symbol)
+		}
+	}
+}
+
+func TestSlotsOfEndedBlocksAreReused(t *testing.T) {
+	function := InitSymbolTable(nil, InitFn(token.Void))
+	_ = function.AddAVariable("a", token.Int)
+
+	first := function.AppendChildSymbolTable(function.RelatedFunction)
+	_ = first.AddAVariable("i", token.Int)
+	_ = first.AddAVariable("d", token.Double)
+	first.ReleaseMemorySlots()
+
+	second := function.AppendChildSymbolTable(function.RelatedFunction)
+	_ = second.AddAVariable("e", token.Double)
+	_ = second.AddAVariable("j", token.Int)
+	_ = second.AddAVariable("k", token.Int)
+
+	for name, address := range map[string]int{"e": 1, "j": 3, "k": 4} {
+		if _, _, got := second.Resolve(name); got != address {
+			t.Errorf("address of '%s' = %d, want %d", name, got, address)
+		}
+	}
+	if size := function.RelatedFunction.FrameSize(); size != 5 {
+		t.Errorf("frame size = %d, want 5", size)
+	}
+}
+
+func TestSlotsOfArraysInEndedBlocksAreKept(t *testing.T) {
+	function := InitSymbolTable(nil, InitFn(token.Void))
+
+	first := function.AppendChildSymbolTable(function.RelatedFunction)
+	_ = first.AddAnArray("a", token.Int, []int{4})
+	_ = first.AddAVariable("i", token.Int)
+	first.ReleaseMemorySlots()
+
+	second := function.AppendChildSymbolTable(function.RelatedFunction)
+	_ = second.AddAVariable("j", token.Int)
+	_ = second.AddAVariable("k", token.Int)
+
+	for name, address := range map[string]int{"j": 1, "k": 2} {
+		if _, _, got := second.Resolve(name); got != address {
+			t.Errorf("address of '%s' = %d, want %d", name, got, address)
+		}
+	}
+}
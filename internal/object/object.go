@@ -0,0 +1,225 @@
+package object
+
+import (
+	"bufio"
+	"bytes"
+	"c0_compiler/internal/instruction"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+var Magic = []byte{0x43, 0x30, 0x3a, 0x29}
+
+// Tags used by the binary format for the entries of the constant pool.
+const (
+	tagString = 0
+	tagInt    = 1
+	tagDouble = 2
+)
+
+type Function struct {
+	NameIndex  int
+	ParamsSize int
+	Level      int
+	Lines      []instruction.Line
+}
+
+// File is the decoded form of a `C0:)` binary.
+type File struct {
+	Version   int
+	Constants []instruction.Constant
+	Start     []instruction.Line
+	Functions []Function
+}
+
+// Returns the name of the function by looking it up in the constant pool.
+func (f *File) NameOf(fn *Function) string {
+	if fn.NameIndex < 0 || fn.NameIndex >= len(f.Constants) {
+		return ""
+	}
+	if name, ok := f.Constants[fn.NameIndex].Value.(string); ok {
+		return name
+	}
+	return ""
+}
+
+// Returns the index of the function named `name` in the function table, or -1 if there is no such function.
+func (f *File) FunctionNamed(name string) int {
+	for index := range f.Functions {
+		if f.NameOf(&f.Functions[index]) == name {
+			return index
+		}
+	}
+	return -1
+}
+
+func IsBinary(content []byte) bool {
+	return bytes.HasPrefix(content, Magic)
+}
+
+type reader struct {
+	r      *bufio.Reader
+	offset int
+}
+
+func (r *reader) fail(format string, args ...interface{}) error {
+	return fmt.Errorf("at byte %d: %s", r.offset, fmt.Sprintf(format, args...))
+}
+
+func (r *reader) readBytes(n int) ([]byte, error) {
+	buffer := make([]byte, n)
+	if _, err := io.ReadFull(r.r, buffer); err != nil {
+		return nil, r.fail("unexpected end of file")
+	}
+	r.offset += n
+	return buffer, nil
+}
+
+// Reads a big-endian unsigned integer that is `width` bytes long.
+func (r *reader) readUnsigned(width int) (int, error) {
+	buffer, err := r.readBytes(width)
+	if err != nil {
+		return 0, err
+	}
+	value := 0
+	for _, b := range buffer {
+		value = value<<8 | int(b)
+	}
+	return value, nil
+}
+
+// Operands that are 4 bytes wide are signed, all the others are unsigned.
+func (r *reader) readOperand(width int) (int, error) {
+	value, err := r.readUnsigned(width)
+	if err != nil {
+		return 0, err
+	}
+	if width == 4 {
+		return int(int32(uint32(value))), nil
+	}
+	return value, nil
+}
+
+func (r *reader) readConstant(index int) (instruction.Constant, error) {
+	tag, err := r.readUnsigned(1)
+	if err != nil {
+		return instruction.Constant{}, err
+	}
+	switch tag {
+	case tagString:
+		length, err := r.readUnsigned(2)
+		if err != nil {
+			return instruction.Constant{}, err
+		}
+		content, err := r.readBytes(length)
+		if err != nil {
+			return instruction.Constant{}, err
+		}
+		return instruction.Constant{Kind: instruction.ConstantKindString, Value: string(content), Address: index}, nil
+	case tagInt:
+		value, err := r.readOperand(4)
+		if err != nil {
+			return instruction.Constant{}, err
+		}
+		return instruction.Constant{Kind: instruction.ConstantKindInt, Value: value, Address: index}, nil
+	case tagDouble:
+		content, err := r.readBytes(8)
+		if err != nil {
+			return instruction.Constant{}, err
+		}
+		value := math.Float64frombits(binary.BigEndian.Uint64(content))
+		return instruction.Constant{Kind: instruction.ConstantKindDouble, Value: value, Address: index}, nil
+	}
+	return instruction.Constant{}, r.fail("unknown constant type %d", tag)
+}
+
+func (r *reader) readInstructions() ([]instruction.Line, error) {
+	count, err := r.readUnsigned(2)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]instruction.Line, 0, count)
+	for i := 0; i < count; i++ {
+		code, err := r.readUnsigned(1)
+		if err != nil {
+			return nil, err
+		}
+		inst, ok := instruction.Instructions[code]
+		if !ok {
+			return nil, r.fail("unknown instruction 0x%02x", code)
+		}
+		operands := make([]int, len(inst.Operands))
+		for index, width := range inst.Operands {
+			if operands[index], err = r.readOperand(width); err != nil {
+				return nil, err
+			}
+		}
+		lines = append(lines, instruction.Line{I: inst, Operands: &operands})
+	}
+	return lines, nil
+}
+
+func (r *reader) readFunction() (fn Function, err error) {
+	if fn.NameIndex, err = r.readUnsigned(2); err != nil {
+		return
+	}
+	if fn.ParamsSize, err = r.readUnsigned(2); err != nil {
+		return
+	}
+	if fn.Level, err = r.readUnsigned(2); err != nil {
+		return
+	}
+	fn.Lines, err = r.readInstructions()
+	return
+}
+
+// Decodes a binary produced by `compiler.Run`.
+func Read(source io.Reader) (*File, error) {
+	r := &reader{r: bufio.NewReader(source)}
+	magic, err := r.readBytes(len(Magic))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, Magic) {
+		return nil, fmt.Errorf("not a C0 binary: bad magic number 0x%x", magic)
+	}
+	file := &File{}
+	if file.Version, err = r.readUnsigned(4); err != nil {
+		return nil, err
+	}
+
+	nConstants, err := r.readUnsigned(2)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < nConstants; i++ {
+		c, err := r.readConstant(i)
+		if err != nil {
+			return nil, err
+		}
+		file.Constants = append(file.Constants, c)
+	}
+
+	if file.Start, err = r.readInstructions(); err != nil {
+		return nil, err
+	}
+
+	nFunctions, err := r.readUnsigned(2)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < nFunctions; i++ {
+		fn, err := r.readFunction()
+		if err != nil {
+			return nil, err
+		}
+		file.Functions = append(file.Functions, fn)
+	}
+
+	if _, err := r.r.ReadByte(); err != io.EOF {
+		return nil, r.fail("trailing bytes after the function table")
+	}
+	return file, nil
+}
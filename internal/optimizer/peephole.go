@@ -0,0 +1,152 @@
+// Package optimizer rewrites the instructions generated for the functions into fewer or cheaper ones that do the
+// same, before they are assembled.
+package optimizer
+
+import "c0_compiler/internal/instruction"
+
+type Line = instruction.Line
+
+// The largest constant pushed by `bipush`, whose operand is a single byte read as unsigned by some VMs and as signed
+// by the others.
+const maxBipushOperand = 127
+
+// Optimizes all the functions of the program, including the code initializing the global variables.
+func Run(globalSymbolTable *instruction.SymbolTable) {
+	optimize(globalSymbolTable.RelatedFunction)
+	for _, sb := range globalSymbolTable.Symbols {
+		if sb.IsCallable {
+			optimize(sb.FnInfo)
+		}
+	}
+}
+
+// Each rewrite may make the others possible, e.g. a jump to a `jmp` which jumps to the next line, so they are
+// repeated until none of them applies.
+func optimize(fn *instruction.Fn) {
+	lines := *fn.GetLines()
+	useBipush(lines)
+	for changed := true; changed; {
+		changed = threadJumps(lines)
+		changed = removeJumpsToNext(lines) || changed
+		changed = foldPushAndPop(lines) || changed
+		var removed bool
+		lines, removed = removeNops(lines)
+		changed = changed || removed
+	}
+	fn.SetLines(lines)
+}
+
+func targetOf(line *Line) int {
+	return (*line.Operands)[0]
+}
+
+// The offsets that the jumps go to.
+func jumpTargetsOf(lines []Line) map[int]bool {
+	targets := map[int]bool{}
+	for index := range lines {
+		if instruction.IsAJump(lines[index].I.Code) {
+			targets[targetOf(&lines[index])] = true
+		}
+	}
+	return targets
+}
+
+// `ipush` takes 5 bytes while `bipush` takes 2.
+func useBipush(lines []Line) {
+	for index := range lines {
+		line := &lines[index]
+		if line.I.Code == instruction.Ipush && targetOf(line) >= 0 && targetOf(line) <= maxBipushOperand {
+			line.ChangeInstructionTo(instruction.Bipush, targetOf(line))
+		}
+	}
+}
+
+// Points a jump to a `jmp` to where the `jmp` goes, e.g. `je 5` with `5: jmp 9` becomes `je 9`.
+func threadJumps(lines []Line) (changed bool) {
+	for index := range lines {
+		line := &lines[index]
+		if !instruction.IsAJump(line.I.Code) {
+			continue
+		}
+		// A loop of `jmp`s is left as it is
+		target := targetOf(line)
+		for hops := 0; hops < len(lines) && target < len(lines) && lines[target].I.Code == instruction.Jmp &&
+			targetOf(&lines[target]) != target; hops++ {
+			target = targetOf(&lines[target])
+		}
+		if target != targetOf(line) {
+			line.SetFirstOperandTo(target)
+			changed = true
+		}
+	}
+	return
+}
+
+// The first line from `offset` on which is not a `nop`.
+func skipNops(lines []Line, offset int) int {
+	for offset < len(lines) && lines[offset].I.Code == instruction.Nop {
+		offset++
+	}
+	return offset
+}
+
+// Drops a jump to where the execution goes anyway. A conditional jump still pops the value it tests.
+func removeJumpsToNext(lines []Line) (changed bool) {
+	for index := range lines {
+		line := &lines[index]
+		if !instruction.IsAJump(line.I.Code) || skipNops(lines, targetOf(line)) != skipNops(lines, index+1) {
+			continue
+		}
+		if line.I.Code == instruction.Jmp {
+			line.ChangeInstructionTo(instruction.Nop)
+		} else {
+			line.ChangeInstructionTo(instruction.Pop)
+		}
+		changed = true
+	}
+	return
+}
+
+// Drops a value which is popped right after it is pushed, e.g. `ipush 1; pop`, and merges the pops. The pop is kept
+// if it is where a jump goes, since the value popped there may be pushed by another path.
+func foldPushAndPop(lines []Line) (changed bool) {
+	targets := jumpTargetsOf(lines)
+	for index := 0; index+1 < len(lines); index++ {
+		push, pop := &lines[index], &lines[index+1]
+		if targets[index+1] {
+			continue
+		}
+		switch {
+		case pop.I.Code == instruction.Pop && isAPushOfASlot(push.I.Code),
+			pop.I.Code == instruction.Pop2 && push.I.Code == instruction.Dup2:
+			push.ChangeInstructionTo(instruction.Nop)
+			pop.ChangeInstructionTo(instruction.Nop)
+		case pop.I.Code == instruction.Pop && (push.I.Code == instruction.Iload || push.I.Code == instruction.Aload),
+			pop.I.Code == instruction.Pop2 && push.I.Code == instruction.Dload:
+			// Only the address remains to be popped
+			push.ChangeInstructionTo(instruction.Nop)
+			pop.ChangeInstructionTo(instruction.Pop)
+		case pop.I.Code == instruction.Pop && push.I.Code == instruction.Pop:
+			push.ChangeInstructionTo(instruction.Nop)
+			pop.ChangeInstructionTo(instruction.Pop2)
+		default:
+			continue
+		}
+		changed = true
+	}
+	return
+}
+
+// Whether the instruction only pushes a single slot without any other effect.
+func isAPushOfASlot(code int) bool {
+	switch code {
+	case instruction.Bipush, instruction.Ipush, instruction.Loada, instruction.Dup:
+		return true
+	}
+	return false
+}
+
+// Drops the `nop`s, and points the jumps to them to the lines after them.
+func removeNops(lines []Line) ([]Line, bool) {
+	return instruction.RemoveLines(lines, func(index int) bool { return lines[index].I.Code != instruction.Nop })
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bufio"
+	"math/big"
 	"c0_compiler/internal/cc0_error"
 	"c0_compiler/internal/token"
 	"math"
@@ -11,8 +12,6 @@ import (
 	"unicode"
 )
 
-var isInACommentBlock = false
-
 type Token = token.Token
 
 type Parser struct {
@@ -48,32 +47,44 @@ func (p *Parser) ResetHeadTo(pos int) *Token {
 	}
 }
 
+// The state of splitting a single source into tokens.
+type lexer struct {
+	diagnostics                          *cc0_error.Diagnostics
+	isInACommentBlock                    bool
+	commentBlockLine, commentBlockColumn int
+}
+
 type parserError struct {
-	message string
-	fatal   bool
+	code  int
+	fatal bool
 }
 
-func reportPosition(token *Token) {
-	cc0_error.ReportLineAndColumn(token.Line, token.Column)
+func (l *lexer) report(code, line, column, endColumn int) *cc0_error.Error {
+	err := cc0_error.Of(code).On(line, column).Until(line, endColumn)
+	l.diagnostics.Report(cc0_error.Parser, err)
+	return err
+}
+
+func (l *lexer) reportAt(code int, t *Token) *cc0_error.Error {
+	return l.report(code, t.Line, t.Column, t.EndColumn)
 }
 
 var decMatcher, _ = regexp.Compile("^(0|([1-9][0-9]*))$")
 var hexMatcher, _ = regexp.Compile("^[0-9a-fA-F]+$")
 var doubleMatcher, _ = regexp.Compile("^[0-9]+\\.[0-9]+$")
 
-// Accepts a regex matcher used to pre-check the format of the given word.
+// Accepts a regex matcher used to pre-check the format of the given word. An int has 32 bits, so a larger literal
+// keeps only its lowest 32 bits as the VM does on overflow, which is returned along with a non-fatal error.
 func parseIntegerValue(matcher *regexp.Regexp, base int, word string) (int64, *parserError) {
-	if matcher.MatchString(word) {
-		value, err := strconv.ParseInt(word, base, 64)
-		if err != nil {
-			if _, ok := err.(*strconv.NumError); ok {
-				return 0, &parserError{"value out of range; set to 0", false}
-			}
-			return 0, &parserError{err.Error(), true}
-		}
-		return value, nil
+	value, ok := new(big.Int).SetString(word, base)
+	if !matcher.MatchString(word) || !ok {
+		return 0, &parserError{cc0_error.IllegalIntegerLiteral, true}
+	}
+	wrapped := int64(int32(new(big.Int).And(value, big.NewInt(math.MaxUint32)).Uint64()))
+	if value.Cmp(big.NewInt(math.MaxInt32)) > 0 {
+		return wrapped, &parserError{cc0_error.IntegerLiteralOutOfRange, false}
 	}
-	return 0, &parserError{"illegal integer literal", true}
+	return wrapped, nil
 }
 
 func parseFloat(str string) (float64, error) {
@@ -109,7 +120,7 @@ func parseFloat(str string) (float64, error) {
 func parseDoubleValue(matcher *regexp.Regexp, word string) (float64, *parserError) {
 	value, err := parseFloat(word)
 	if err != nil {
-		return 0, &parserError{"Failed to parse double value", true}
+		return 0, &parserError{cc0_error.IllegalDoubleLiteral, true}
 	}
 	return value, nil
 }
@@ -128,7 +139,7 @@ func doubleParser(word string) (float64, *parserError) {
 	return parseDoubleValue(doubleMatcher, word)
 }
 
-func parseIntegerLiteral(currentToken *Token) {
+func (l *lexer) parseIntegerLiteral(currentToken *Token) {
 	var integerParser func(string) (int64, *parserError)
 	word := currentToken.Value.(string)
 
@@ -142,28 +153,26 @@ func parseIntegerLiteral(currentToken *Token) {
 	currentToken.Kind = token.IntegerLiteral
 	currentToken.Value = parsedValue
 	if err != nil {
-		reportPosition(currentToken)
-		cc0_error.PrintlnToStdErr(err.message)
 		if err.fatal {
-			cc0_error.ThrowAndExit(cc0_error.Parser)
+			l.reportAt(err.code, currentToken).WithMessage("Illegal integer literal '%s'.", word)
 		} else {
-			cc0_error.ThrowButStayAlive(cc0_error.Parser)
+			l.diagnostics.Warn(cc0_error.Parser, cc0_error.WarnLiteralRange, cc0_error.Of(err.code).
+				On(currentToken.Line, currentToken.Column).Until(currentToken.Line, currentToken.EndColumn).
+				WithMessage("The integer literal '%s' does not fit in an int and becomes %d.", word, parsedValue))
 		}
 	}
 }
 
-func parseDoubleLiteral(currentToken *Token) {
+func (l *lexer) parseDoubleLiteral(currentToken *Token) {
 	parsedValue, err := doubleParser(currentToken.Value.(string))
 	if err != nil {
-		reportPosition(currentToken)
-		cc0_error.PrintlnToStdErr(err.message)
-		cc0_error.ThrowAndExit(cc0_error.Parser)
+		l.reportAt(err.code, currentToken)
 	}
 	currentToken.Kind = token.DoubleLiteral
 	currentToken.Value = parsedValue
 }
 
-func parseOperator(currentToken *Token) {
+func (l *lexer) parseOperator(currentToken *Token) {
 	kind := &currentToken.Kind
 
 	switch word := currentToken.Value.(string); word {
@@ -175,6 +184,8 @@ func parseOperator(currentToken *Token) {
 		*kind = token.MultiplicationSign
 	case "/":
 		*kind = token.DivisionSign
+	case "%":
+		*kind = token.ModuloSign
 	case "=":
 		*kind = token.AssignmentSign
 	case "(":
@@ -185,6 +196,10 @@ func parseOperator(currentToken *Token) {
 		*kind = token.LeftBracket
 	case "}":
 		*kind = token.RightBracket
+	case "[":
+		*kind = token.LeftSquareBracket
+	case "]":
+		*kind = token.RightSquareBracket
 	case ">":
 		*kind = token.GreaterThan
 	case ">=":
@@ -197,14 +212,36 @@ func parseOperator(currentToken *Token) {
 		*kind = token.LessThan
 	case "!=":
 		*kind = token.NotEqualTo
+	case "&&":
+		*kind = token.LogicalAnd
+	case "||":
+		*kind = token.LogicalOr
+	case "!":
+		*kind = token.LogicalNot
+	case "&":
+		*kind = token.BitwiseAnd
+	case "|":
+		*kind = token.BitwiseOr
+	case "^":
+		*kind = token.BitwiseXor
+	case "~":
+		*kind = token.BitwiseNot
+	case "<<":
+		*kind = token.LeftShift
+	case ">>":
+		*kind = token.RightShift
 	case ",":
 		*kind = token.Comma
 	case ";":
 		*kind = token.Semicolon
+	case ":":
+		*kind = token.Colon
+	case ".":
+		*kind = token.Dot
 	default:
-		reportPosition(currentToken)
-		cc0_error.PrintfToStdErr("Unrecognized character '%s'\n", word)
-		cc0_error.ThrowAndExit(cc0_error.Parser)
+		// The token stays unparsed and will be dropped
+		l.reportAt(cc0_error.UnrecognizedCharacter, currentToken).WithMessage(
+			"Unrecognized character '%s'.", word)
 	}
 }
 
@@ -255,7 +292,7 @@ func parseKeywords(currentToken *Token) {
 	}
 }
 
-func parseAllTheTokensIn(buffer []Token) {
+func (l *lexer) parseAllTheTokensIn(buffer []Token) {
 	for ind := range buffer {
 		currentToken := &buffer[ind]
 
@@ -263,18 +300,18 @@ func parseAllTheTokensIn(buffer []Token) {
 			continue
 		}
 		word := currentToken.Value.(string)
-		if strings.ContainsRune(word, '.') ||
+		if (strings.ContainsRune(word, '.') && word != ".") ||
 			(unicode.IsNumber(rune(word[0])) &&
 				len(word) > 2 &&
 				strings.ToLower(word[0:2]) != "0x" &&
 				strings.ContainsRune(strings.ToLower(word), 'e')) {
-			parseDoubleLiteral(currentToken)
+			l.parseDoubleLiteral(currentToken)
 		} else if unicode.IsNumber(rune(word[0])) {
-			parseIntegerLiteral(currentToken)
+			l.parseIntegerLiteral(currentToken)
 		} else if unicode.IsLetter(rune(word[0])) {
 			parseKeywords(currentToken)
 		} else {
-			parseOperator(currentToken)
+			l.parseOperator(currentToken)
 		}
 	}
 }
@@ -283,9 +320,14 @@ func isDigitOrLetter(character rune) bool {
 	return unicode.IsNumber(character) || unicode.IsLetter(character) || character == '.'
 }
 
+// A '.' which is not followed by a digit accesses a member, e.g. `p.x`, instead of starting a double literal like `.5`.
+func isAMemberAccess(line string, column int) bool {
+	return line[column] == '.' && (column+1 >= len(line) || !unicode.IsNumber(rune(line[column+1])))
+}
+
 func isAnOperatorWithTwoCharacters(operator string) bool {
 	switch operator {
-	case "<=", ">=", "==", "!=", "//", "/*", "*/":
+	case "<=", ">=", "==", "!=", "&&", "||", "<<", ">>", "//", "/*", "*/":
 		return true
 	}
 	return false
@@ -358,19 +400,15 @@ func parseStringLiteral(line string, start int) ([]rune, int, bool) {
 	return res, -1, false
 }
 
-func reportIllegalCharLiteral(lineCount, columnCount int) {
-	cc0_error.ReportLineAndColumn(lineCount, columnCount)
-	cc0_error.PrintlnToStdErr("Illegal character literal.")
-	cc0_error.ThrowAndExit(cc0_error.Parser)
-}
-
-func reportIllegalStringLiteral(lineCount, columnCount int) {
-	cc0_error.ReportLineAndColumn(lineCount, columnCount)
-	cc0_error.PrintlnToStdErr("Illegal string literal.")
-	cc0_error.ThrowAndExit(cc0_error.Parser)
+// Skips to the closing quote of an illegal literal, or to the end of the line if there isn't one.
+func skipIllegalLiteral(line string, start int, quote byte) int {
+	if end := strings.IndexByte(line[start:], quote); end >= 0 {
+		return start + end + 1
+	}
+	return len(line)
 }
 
-func divideTokens(lineCount int, line string, buffer *[]Token) {
+func (l *lexer) divideTokens(lineCount int, line string, buffer *[]Token) {
 	columnCount := 0
 	for columnCount < len(line) {
 		character := rune(line[columnCount])
@@ -380,10 +418,12 @@ func divideTokens(lineCount int, line string, buffer *[]Token) {
 			continue
 		}
 		end := columnCount
-		if isDigitOrLetter(character) {
+		if isDigitOrLetter(character) && !isAMemberAccess(line, columnCount) {
+			// Neither the '.' in `p.x` nor the '-' in `size-1` is a part of the identifier
+			isAWord := unicode.IsLetter(character)
 			previousCharacter := '0'
-			for end < len(line) && (isDigitOrLetter(rune(line[end])) ||
-				((previousCharacter == 'e' || previousCharacter == 'E') && line[end] == '-')) {
+			for end < len(line) && (isDigitOrLetter(rune(line[end])) && !(isAWord && line[end] == '.') ||
+				(!isAWord && (previousCharacter == 'e' || previousCharacter == 'E') && line[end] == '-')) {
 				previousCharacter = rune(line[end])
 				end++
 			}
@@ -396,6 +436,7 @@ func divideTokens(lineCount int, line string, buffer *[]Token) {
 		}
 
 		currentTokenString := line[columnCount:end]
+		start := columnCount
 		columnCount = end
 
 		if currentTokenString == "//" {
@@ -404,70 +445,105 @@ func divideTokens(lineCount int, line string, buffer *[]Token) {
 		}
 
 		if currentTokenString == "/*" {
-			isInACommentBlock = true
+			if !l.isInACommentBlock {
+				l.commentBlockLine, l.commentBlockColumn = lineCount, start+1
+			}
+			l.isInACommentBlock = true
 			continue
 		} else if currentTokenString == "*/" {
-			if !isInACommentBlock {
-				cc0_error.ReportLineAndColumn(lineCount, columnCount)
-				cc0_error.PrintlnToStdErr("encountered an illegal comment block.")
-				cc0_error.ThrowAndExit(cc0_error.Parser)
+			if !l.isInACommentBlock {
+				l.report(cc0_error.IllegalCommentBlock, lineCount, start+1, end+1)
 			}
-			isInACommentBlock = false
+			l.isInACommentBlock = false
 			continue
 		}
 
-		if isInACommentBlock {
+		if l.isInACommentBlock {
 			continue
 		}
 		if currentTokenString == "'" {
-			if columnCount+2 >= len(line) {
-				reportIllegalCharLiteral(lineCount, columnCount)
+			var parsed rune = -1
+			end := 0
+			if columnCount+1 < len(line) {
+				parsed, end = parseCharLiteral(line, columnCount-1)
 			}
-			parsed, end := parseCharLiteral(line, columnCount-1)
 			if parsed < 0 {
-				reportIllegalCharLiteral(lineCount, columnCount)
+				columnCount = skipIllegalLiteral(line, columnCount, '\'')
+				l.report(cc0_error.IllegalCharLiteral, lineCount, start+1, columnCount+1)
+				continue
 			}
 			columnCount = end
 			*buffer = append(*buffer, Token{
-				Kind:   token.CharLiteral,
-				Value:  parsed,
-				Line:   lineCount,
-				Column: columnCount,
+				Kind:      token.CharLiteral,
+				Value:     parsed,
+				Line:      lineCount,
+				Column:    start + 1,
+				EndColumn: end + 1,
 			})
 		} else if currentTokenString == "\"" {
 			parsed, end, ok := parseStringLiteral(line, columnCount-1)
 			if !ok {
-				reportIllegalStringLiteral(lineCount, columnCount)
+				l.report(cc0_error.IllegalStringLiteral, lineCount, start+1, len(line)+1)
+				return
 			}
 			*buffer = append(*buffer, Token{
-				Kind:   token.StringLiteral,
-				Value:  string(parsed),
-				Line:   lineCount,
-				Column: columnCount,
+				Kind:      token.StringLiteral,
+				Value:     string(parsed),
+				Line:      lineCount,
+				Column:    start + 1,
+				EndColumn: end + 1,
 			})
 			columnCount = end
 		} else {
 			*buffer = append(*buffer, Token{
-				Kind:   token.NotParsed,
-				Value:  currentTokenString,
-				Line:   lineCount,
-				Column: columnCount + 1,
+				Kind:      token.NotParsed,
+				Value:     currentTokenString,
+				Line:      lineCount,
+				Column:    start + 1,
+				EndColumn: end + 1,
 			})
 		}
 	}
 }
 
-func Parse(scanner *bufio.Scanner) (parser *Parser) {
+// Problems in the source are reported into `d`, the tokens that cannot be parsed are dropped.
+func Parse(scanner *bufio.Scanner, d *cc0_error.Diagnostics) (parser *Parser) {
 	buffer := make([]Token, 0)
 	lineCount := 0
+	l := &lexer{diagnostics: d}
 
 	for scanner.Scan() {
 		lineCount++
 		line := scanner.Text()
-		divideTokens(lineCount, line, &buffer)
+		l.divideTokens(lineCount, line, &buffer)
+	}
+	if l.isInACommentBlock {
+		l.report(cc0_error.UnterminatedCommentBlock, l.commentBlockLine, l.commentBlockColumn, l.commentBlockColumn+2)
+	}
+	l.parseAllTheTokensIn(buffer)
+
+	parsed := buffer[:0]
+	for _, t := range buffer {
+		if t.Kind != token.NotParsed {
+			parsed = append(parsed, t)
+		}
 	}
-	parseAllTheTokensIn(buffer)
-	parser = &Parser{buffer, 0}
+	parser = &Parser{parsed, 0}
 
 	return
 }
+
+// Returns the token starting at the given position, or nil if there is none.
+func (p *Parser) TokenStartingAt(line, column int) *Token {
+	for index := range p.buffer {
+		if t := &p.buffer[index]; t.Line == line && t.Column == column {
+			return t
+		}
+	}
+	return nil
+}
+
+// All the tokens that have been parsed, in the order of the source.
+func (p *Parser) Tokens() []Token {
+	return p.buffer
+}
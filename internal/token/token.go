@@ -6,19 +6,33 @@ const (
 	MinusSign
 	MultiplicationSign
 	DivisionSign
+	ModuloSign
 	LessThan
 	LessThanOrEqual
 	EqualTo
 	GreaterThanOrEqual
 	GreaterThan
 	NotEqualTo
+	LogicalAnd
+	LogicalOr
+	LogicalNot
+	BitwiseAnd
+	BitwiseOr
+	BitwiseXor
+	BitwiseNot
+	LeftShift
+	RightShift
 	AssignmentSign
 	LeftBracket
 	RightBracket
+	LeftSquareBracket
+	RightSquareBracket
 	LeftParenthesis
 	RightParenthesis
 	Comma
 	Semicolon
+	Colon
+	Dot
 	IntegerLiteral
 	DoubleLiteral
 	Const
@@ -45,13 +59,81 @@ const (
 	Identifier
 )
 
+var kindNames = map[int]string{
+	PlusSign:           "PlusSign",
+	MinusSign:          "MinusSign",
+	MultiplicationSign: "MultiplicationSign",
+	DivisionSign:       "DivisionSign",
+	ModuloSign:         "ModuloSign",
+	LessThan:           "LessThan",
+	LessThanOrEqual:    "LessThanOrEqual",
+	EqualTo:            "EqualTo",
+	GreaterThanOrEqual: "GreaterThanOrEqual",
+	GreaterThan:        "GreaterThan",
+	NotEqualTo:         "NotEqualTo",
+	LogicalAnd:         "LogicalAnd",
+	LogicalOr:          "LogicalOr",
+	LogicalNot:         "LogicalNot",
+	BitwiseAnd:         "BitwiseAnd",
+	BitwiseOr:          "BitwiseOr",
+	BitwiseXor:         "BitwiseXor",
+	BitwiseNot:         "BitwiseNot",
+	LeftShift:          "LeftShift",
+	RightShift:         "RightShift",
+	AssignmentSign:     "AssignmentSign",
+	LeftBracket:        "LeftBracket",
+	RightBracket:       "RightBracket",
+	LeftSquareBracket:  "LeftSquareBracket",
+	RightSquareBracket: "RightSquareBracket",
+	LeftParenthesis:    "LeftParenthesis",
+	RightParenthesis:   "RightParenthesis",
+	Comma:              "Comma",
+	Semicolon:          "Semicolon",
+	Colon:              "Colon",
+	Dot:                "Dot",
+	IntegerLiteral:     "IntegerLiteral",
+	DoubleLiteral:      "DoubleLiteral",
+	Const:              "Const",
+	Void:               "Void",
+	Char:               "Char",
+	Int:                "Int",
+	Double:             "Double",
+	CharLiteral:        "CharLiteral",
+	StringLiteral:      "StringLiteral",
+	Struct:             "Struct",
+	If:                 "If",
+	Else:               "Else",
+	Switch:             "Switch",
+	Case:               "Case",
+	Default:            "Default",
+	While:              "While",
+	For:                "For",
+	Do:                 "Do",
+	Return:             "Return",
+	Break:              "Break",
+	Continue:           "Continue",
+	Print:              "Print",
+	Scan:               "Scan",
+	Identifier:         "Identifier",
+}
+
+// The name of the kind of tokens, e.g. "LeftParenthesis".
+func KindName(kind int) string {
+	if name, ok := kindNames[kind]; ok {
+		return name
+	}
+	return "NotParsed"
+}
+
 type any = interface{}
 
+// `Column` is where the token starts and `EndColumn` is right after where it ends, both count from 1.
 type Token struct {
-	Kind   int
-	Value  any
-	Line   int
-	Column int
+	Kind      int
+	Value     any
+	Line      int
+	Column    int
+	EndColumn int
 }
 
 func (t *Token) IsATypeSpecifier() bool {
@@ -73,11 +155,15 @@ func (t *Token) IsARelationalOperator() bool {
 }
 
 func (t *Token) IsAnUnaryOperator() bool {
-	return t.Kind == PlusSign || t.Kind == MinusSign
+	return t.Kind == PlusSign || t.Kind == MinusSign || t.Kind == BitwiseNot
 }
 
 func (t *Token) IsAMultiplicativeOperator() bool {
-	return t.Kind == MultiplicationSign || t.Kind == DivisionSign
+	return t.Kind == MultiplicationSign || t.Kind == DivisionSign || t.Kind == ModuloSign
+}
+
+func (t *Token) IsAShiftOperator() bool {
+	return t.Kind == LeftShift || t.Kind == RightShift
 }
 
 func (t *Token) IsAnAdditiveOperator() bool {
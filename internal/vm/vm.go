@@ -0,0 +1,481 @@
+package vm
+
+import (
+	"bufio"
+	"bytes"
+	"c0_compiler/internal/compiler"
+	"c0_compiler/internal/instruction"
+	"c0_compiler/internal/object"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"unicode"
+)
+
+// Every value lives in 32-bit slots. Doubles take two consecutive slots, the higher half comes first.
+// The stack occupies the addresses in [0, stackSize) and the heap starts right after it.
+const (
+	stackSize = 1 << 20
+	heapBase  = stackSize
+)
+
+type RuntimeError struct {
+	Function string
+	Offset   int
+	Message  string
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("Runtime error in %s at offset %d: %s", e.Function, e.Offset, e.Message)
+}
+
+type frame struct {
+	function int // -1 for the `.start` section
+	pc       int
+	base     int
+	level    int
+}
+
+type machine struct {
+	file           *object.File
+	stack          []int32
+	sp             int
+	heap           []int32
+	frames         []frame
+	constantHeaps  map[int]int32 // addresses of the string constants
+	in             *bufio.Reader
+	out            *bufio.Writer
+	currentOffset  int
+	currentSection string
+}
+
+// Assembles the text produced by `assembler.Run` so that it can be executed.
+func LoadAssembly(lines *[]string) (*object.File, error) {
+	var buffer bytes.Buffer
+	w := bufio.NewWriter(&buffer)
+	if err := compiler.RunOnGeneratedAssembly(lines, w); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return object.Read(&buffer)
+}
+
+// Executes the `.start` section and then `main`, reading from `in` and writing to `out`.
+func Run(file *object.File, in io.Reader, out io.Writer) (err error) {
+	m := &machine{
+		file:          file,
+		stack:         make([]int32, stackSize),
+		constantHeaps: map[int]int32{},
+		in:            bufio.NewReader(in),
+		out:           bufio.NewWriter(out),
+	}
+	defer func() {
+		if flushErr := m.out.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeErr, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			err = runtimeErr
+		}
+	}()
+
+	for index, c := range file.Constants {
+		if c.Kind == instruction.ConstantKindString {
+			m.constantHeaps[index] = m.allocateString(c.Value.(string))
+		}
+	}
+
+	main := file.FunctionNamed("main")
+	if main < 0 {
+		return &RuntimeError{Function: ".start", Message: "no main function is defined"}
+	}
+
+	m.frames = append(m.frames, frame{function: -1, level: 0})
+	m.execute()
+	m.call(main)
+	m.execute()
+	return nil
+}
+
+func (m *machine) fail(format string, args ...interface{}) {
+	panic(&RuntimeError{Function: m.currentSection, Offset: m.currentOffset, Message: fmt.Sprintf(format, args...)})
+}
+
+func (m *machine) currentFrame() *frame {
+	return &m.frames[len(m.frames)-1]
+}
+
+func (m *machine) linesOf(f *frame) []instruction.Line {
+	if f.function < 0 {
+		return m.file.Start
+	}
+	return m.file.Functions[f.function].Lines
+}
+
+func (m *machine) sectionNameOf(f *frame) string {
+	if f.function < 0 {
+		return ".start"
+	}
+	if name := m.file.NameOf(&m.file.Functions[f.function]); name != "" {
+		return name
+	}
+	return fmt.Sprintf(".F%d", f.function)
+}
+
+func (m *machine) call(index int) {
+	if index < 0 || index >= len(m.file.Functions) {
+		m.fail("call to an undefined function %d", index)
+	}
+	fn := &m.file.Functions[index]
+	base := m.sp - fn.ParamsSize
+	if base < m.currentFrame().base {
+		m.fail("not enough arguments on the stack to call %s", m.file.NameOf(fn))
+	}
+	m.frames = append(m.frames, frame{function: index, base: base, level: fn.Level})
+}
+
+func (m *machine) leave() {
+	m.sp = m.currentFrame().base
+	m.frames = m.frames[:len(m.frames)-1]
+}
+
+// Follows the static links `levelDiff` times, starting from the current frame.
+func (m *machine) frameAt(levelDiff int) *frame {
+	index := len(m.frames) - 1
+	for ; levelDiff > 0; levelDiff-- {
+		level := m.frames[index].level
+		for index >= 0 && m.frames[index].level >= level {
+			index--
+		}
+		if index < 0 {
+			m.fail("level difference out of range")
+		}
+	}
+	return &m.frames[index]
+}
+
+func (m *machine) push(value int32) {
+	if m.sp >= stackSize {
+		m.fail("stack overflow")
+	}
+	m.stack[m.sp] = value
+	m.sp++
+}
+
+func (m *machine) pop() int32 {
+	if m.sp <= m.currentFrame().base {
+		m.fail("stack underflow")
+	}
+	m.sp--
+	return m.stack[m.sp]
+}
+
+func (m *machine) pushDouble(value float64) {
+	bits := math.Float64bits(value)
+	m.push(int32(bits >> 32))
+	m.push(int32(bits))
+}
+
+func (m *machine) popDouble() float64 {
+	low := uint32(m.pop())
+	high := uint32(m.pop())
+	return math.Float64frombits(uint64(high)<<32 | uint64(low))
+}
+
+func (m *machine) slot(address int32) *int32 {
+	if address >= 0 && int(address) < m.sp {
+		return &m.stack[address]
+	}
+	if offset := int(address) - heapBase; offset >= 0 && offset < len(m.heap) {
+		return &m.heap[offset]
+	}
+	m.fail("invalid memory access at address %d", address)
+	return nil
+}
+
+func (m *machine) load(address int32) int32 {
+	return *m.slot(address)
+}
+
+func (m *machine) store(address, value int32) {
+	*m.slot(address) = value
+}
+
+func (m *machine) loadDouble(address int32) float64 {
+	high := uint32(m.load(address))
+	low := uint32(m.load(address + 1))
+	return math.Float64frombits(uint64(high)<<32 | uint64(low))
+}
+
+func (m *machine) storeDouble(address int32, value float64) {
+	bits := math.Float64bits(value)
+	m.store(address, int32(bits>>32))
+	m.store(address+1, int32(bits))
+}
+
+func (m *machine) allocate(size int) int32 {
+	if size < 0 {
+		m.fail("cannot allocate %d slots", size)
+	}
+	address := heapBase + len(m.heap)
+	if address+size > math.MaxInt32 {
+		m.fail("out of memory")
+	}
+	m.heap = append(m.heap, make([]int32, size)...)
+	return int32(address)
+}
+
+func (m *machine) allocateString(value string) int32 {
+	address := m.allocate(len(value) + 1)
+	for i := 0; i < len(value); i++ {
+		m.heap[int(address)-heapBase+i] = int32(value[i])
+	}
+	return address
+}
+
+func (m *machine) loadConstant(index int) {
+	if index < 0 || index >= len(m.file.Constants) {
+		m.fail("constant %d does not exist", index)
+	}
+	c := m.file.Constants[index]
+	switch c.Kind {
+	case instruction.ConstantKindInt:
+		m.push(int32(c.Value.(int)))
+	case instruction.ConstantKindDouble:
+		m.pushDouble(c.Value.(float64))
+	case instruction.ConstantKindString:
+		m.push(m.constantHeaps[index])
+	}
+}
+
+func compare(lhs, rhs float64) int32 {
+	if lhs > rhs {
+		return 1
+	} else if lhs < rhs {
+		return -1
+	}
+	return 0
+}
+
+func doubleToInt(value float64) int32 {
+	if math.IsNaN(value) {
+		return 0
+	} else if value >= math.MaxInt32 {
+		return math.MaxInt32
+	} else if value <= math.MinInt32 {
+		return math.MinInt32
+	}
+	return int32(value)
+}
+
+func (m *machine) jumpIf(condition bool, target int) {
+	if condition {
+		m.currentFrame().pc = target
+	}
+}
+
+func (m *machine) skipSpaces() {
+	for {
+		r, _, err := m.in.ReadRune()
+		if err != nil {
+			return
+		}
+		if !unicode.IsSpace(r) {
+			_ = m.in.UnreadRune()
+			return
+		}
+	}
+}
+
+func (m *machine) scan(target interface{}) {
+	m.skipSpaces()
+	if _, err := fmt.Fscan(m.in, target); err != nil {
+		m.fail("failed to read the input: %s", err)
+	}
+}
+
+// Runs until the frame which is active at the time of the call returns, or until the `.start` section ends.
+func (m *machine) execute() {
+	depth := len(m.frames)
+	for len(m.frames) >= depth {
+		f := m.currentFrame()
+		lines := m.linesOf(f)
+		m.currentSection, m.currentOffset = m.sectionNameOf(f), f.pc
+		if f.pc >= len(lines) || f.pc < 0 {
+			if f.function < 0 {
+				return
+			}
+			m.fail("control reached the end of the function without returning")
+		}
+		line := lines[f.pc]
+		f.pc++
+		operands := *line.Operands
+
+		switch line.I.Code {
+		case instruction.Nop:
+		case instruction.Bipush, instruction.Ipush:
+			m.push(int32(operands[0]))
+		case instruction.Pop:
+			m.pop()
+		case instruction.Pop2:
+			m.pop()
+			m.pop()
+		case instruction.Popn:
+			for i := 0; i < operands[0]; i++ {
+				m.pop()
+			}
+		case instruction.Dup:
+			value := m.pop()
+			m.push(value)
+			m.push(value)
+		case instruction.Dup2:
+			value := m.popDouble()
+			m.pushDouble(value)
+			m.pushDouble(value)
+		case instruction.Loadc:
+			m.loadConstant(operands[0])
+		case instruction.Loada:
+			m.push(int32(m.frameAt(operands[0]).base + operands[1]))
+		case instruction.New:
+			m.push(m.allocate(int(m.pop())))
+		case instruction.Snew:
+			for i := 0; i < operands[0]; i++ {
+				m.push(0)
+			}
+		case instruction.Iload, instruction.Aload:
+			m.push(m.load(m.pop()))
+		case instruction.Dload:
+			m.pushDouble(m.loadDouble(m.pop()))
+		case instruction.Iaload, instruction.Aaload:
+			index := m.pop()
+			m.push(m.load(m.pop() + index))
+		case instruction.Daload:
+			index := m.pop()
+			m.pushDouble(m.loadDouble(m.pop() + 2*index))
+		case instruction.Istore, instruction.Astore:
+			value := m.pop()
+			m.store(m.pop(), value)
+		case instruction.Dstore:
+			value := m.popDouble()
+			m.storeDouble(m.pop(), value)
+		case instruction.Iastore, instruction.Aastore:
+			value := m.pop()
+			index := m.pop()
+			m.store(m.pop()+index, value)
+		case instruction.Dastore:
+			value := m.popDouble()
+			index := m.pop()
+			m.storeDouble(m.pop()+2*index, value)
+		case instruction.Iadd:
+			rhs := m.pop()
+			m.push(m.pop() + rhs)
+		case instruction.Dadd:
+			rhs := m.popDouble()
+			m.pushDouble(m.popDouble() + rhs)
+		case instruction.Isub:
+			rhs := m.pop()
+			m.push(m.pop() - rhs)
+		case instruction.Dsub:
+			rhs := m.popDouble()
+			m.pushDouble(m.popDouble() - rhs)
+		case instruction.Imul:
+			rhs := m.pop()
+			m.push(m.pop() * rhs)
+		case instruction.Dmul:
+			rhs := m.popDouble()
+			m.pushDouble(m.popDouble() * rhs)
+		case instruction.Idiv:
+			rhs := m.pop()
+			if rhs == 0 {
+				m.fail("division by zero")
+			}
+			m.push(m.pop() / rhs)
+		case instruction.Ddiv:
+			rhs := m.popDouble()
+			m.pushDouble(m.popDouble() / rhs)
+		case instruction.Ineg:
+			m.push(-m.pop())
+		case instruction.Dneg:
+			m.pushDouble(-m.popDouble())
+		case instruction.Icmp:
+			rhs := m.pop()
+			m.push(compare(float64(m.pop()), float64(rhs)))
+		case instruction.Dcmp:
+			rhs := m.popDouble()
+			m.push(compare(m.popDouble(), rhs))
+		case instruction.I2d:
+			m.pushDouble(float64(m.pop()))
+		case instruction.D2i:
+			m.push(doubleToInt(m.popDouble()))
+		case instruction.I2c:
+			m.push(int32(uint8(m.pop())))
+		case instruction.Jmp:
+			m.jumpIf(true, operands[0])
+		case instruction.Je:
+			m.jumpIf(m.pop() == 0, operands[0])
+		case instruction.Jne:
+			m.jumpIf(m.pop() != 0, operands[0])
+		case instruction.Jl:
+			m.jumpIf(m.pop() < 0, operands[0])
+		case instruction.Jge:
+			m.jumpIf(m.pop() >= 0, operands[0])
+		case instruction.Jg:
+			m.jumpIf(m.pop() > 0, operands[0])
+		case instruction.Jle:
+			m.jumpIf(m.pop() <= 0, operands[0])
+		case instruction.Call:
+			m.call(operands[0])
+		case instruction.Ret:
+			m.leave()
+		case instruction.Iret, instruction.Aret:
+			value := m.pop()
+			m.leave()
+			m.push(value)
+		case instruction.Dret:
+			value := m.popDouble()
+			m.leave()
+			m.pushDouble(value)
+		case instruction.Iprint:
+			_, _ = m.out.WriteString(strconv.Itoa(int(m.pop())))
+		case instruction.Dprint:
+			_, _ = m.out.WriteString(strconv.FormatFloat(m.popDouble(), 'g', 6, 64))
+		case instruction.Cprint:
+			_, _ = m.out.WriteRune(rune(m.pop()))
+		case instruction.Sprint:
+			for address := m.pop(); ; address++ {
+				character := m.load(address)
+				if character == 0 {
+					break
+				}
+				_, _ = m.out.WriteRune(rune(character))
+			}
+		case instruction.Printl:
+			_ = m.out.WriteByte('\n')
+		case instruction.Iscan:
+			var value int32
+			m.scan(&value)
+			m.push(value)
+		case instruction.Dscan:
+			var value float64
+			m.scan(&value)
+			m.pushDouble(value)
+		case instruction.Cscan:
+			m.skipSpaces()
+			r, _, err := m.in.ReadRune()
+			if err != nil {
+				m.fail("failed to read the input: %s", err)
+			}
+			m.push(int32(r))
+		default:
+			m.fail("unknown instruction 0x%02x", line.I.Code)
+		}
+	}
+}
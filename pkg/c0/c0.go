@@ -0,0 +1,163 @@
+// Package c0 compiles C0 sources in-process. Unlike the `cc0` command, it never terminates the process: problems in
+// the source are returned as diagnostics, and every compilation keeps its own state so that many of them can run at
+// the same time.
+package c0
+
+import (
+	"bufio"
+	"bytes"
+	"c0_compiler/internal/analyzer"
+	"c0_compiler/internal/assembler"
+	"c0_compiler/internal/cc0_error"
+	"c0_compiler/internal/compiler"
+	"c0_compiler/internal/optimizer"
+	"c0_compiler/internal/parser"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// ErrCompilationFailed is returned by `Compile` when at least one of the diagnostics is an error.
+var ErrCompilationFailed = errors.New("c0: compilation failed")
+
+type Options struct {
+	// The name of the source file, which is only used in the diagnostics.
+	FileName string
+	// Stops collecting errors after this many of them, 0 means there is no limit.
+	ErrorLimit int
+	// Runs the peephole optimizations, the same as `cc0 -O1`.
+	Optimize bool
+	// Reports the warnings as errors, the same as `cc0 -Werror`.
+	WarningsAsErrors bool
+	// Enables or disables the categories of the warnings by their names, e.g. "shadow" is true for `cc0 -Wshadow`.
+	// The categories left out are enabled as `cc0` does by default.
+	Warnings map[string]bool
+}
+
+type Result struct {
+	// The text assembly, the same as what `cc0 -s` outputs.
+	Assembly string
+	// The `C0:)` binary, the same as what `cc0 -c` outputs.
+	Binary []byte
+}
+
+// Diagnostic is an error, a warning, or a note or hint attached to one of them. Lines and columns count from 1 and
+// are 0 when the diagnostic is not about a specific position. The span ends right before `EndLine:EndColumn`.
+type Diagnostic struct {
+	Severity  string // one of "error", "warning", "note" and "hint"
+	Code      string // the name of the error code, e.g. "UndefinedIdentifier"
+	Message   string
+	Phase     string // one of "Source", "Parser", "Analyzer" and "Assembler"
+	Option    string // the option which enables the warning, e.g. "-Wshadow", empty for the others
+	File      string
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+	Notes     []Diagnostic
+}
+
+// Formats the diagnostic like `file:line:column: severity: message`.
+func (d Diagnostic) String() string {
+	var sb strings.Builder
+	if d.File != "" {
+		sb.WriteString(d.File + ":")
+	}
+	if d.Line > 0 {
+		sb.WriteString(fmt.Sprintf("%d:%d:", d.Line, d.Column))
+	}
+	if sb.Len() > 0 {
+		sb.WriteString(" ")
+	}
+	sb.WriteString(d.Severity + ": " + d.Message)
+	return sb.String()
+}
+
+func diagnosticOf(err *cc0_error.Error, file string) Diagnostic {
+	d := Diagnostic{
+		Severity:  cc0_error.SeverityName(err.Severity()),
+		Code:      cc0_error.CodeName(err.Code()),
+		Message:   err.Error(),
+		Phase:     cc0_error.PhaseName(err.From()),
+		Option:    err.Option(),
+		File:      file,
+		Line:      err.Line(),
+		Column:    err.Column(),
+		EndLine:   err.EndLine(),
+		EndColumn: err.EndColumn(),
+	}
+	for _, note := range err.Notes() {
+		noteDiagnostic := diagnosticOf(note, file)
+		noteDiagnostic.Code, noteDiagnostic.Phase = "", d.Phase
+		if !note.HasPosition() {
+			noteDiagnostic.File = ""
+		}
+		d.Notes = append(d.Notes, noteDiagnostic)
+	}
+	return d
+}
+
+// Compiles the C0 source read from `src`. The diagnostics are sorted by their positions and are returned even if the
+// compilation succeeds, since there may be warnings. The error is `ErrCompilationFailed` if any of the diagnostics is
+// an error, the error from reading `src`, or an error about an unknown name in `opts.Warnings`.
+func Compile(src io.Reader, opts Options) (result *Result, diagnostics []Diagnostic, err error) {
+	content, err := ioutil.ReadAll(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	d := cc0_error.NewDiagnostics(opts.FileName, opts.ErrorLimit)
+	d.SetWarningsAsErrors(opts.WarningsAsErrors)
+	for name, enabled := range opts.Warnings {
+		category, ok := cc0_error.ParseWarning(name)
+		if !ok {
+			return nil, nil, fmt.Errorf("c0: unknown warning: %s", name)
+		}
+		d.SetWarningEnabled(category, enabled)
+	}
+	defer func() {
+		// Only a bug in the compiler can lead here
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("c0: internal compiler error: %v", r)
+		}
+	}()
+
+	result = compile(content, d, opts.Optimize)
+	for _, e := range d.All() {
+		diagnostics = append(diagnostics, diagnosticOf(e, opts.FileName))
+	}
+	if d.HasErrors() {
+		return nil, diagnostics, ErrCompilationFailed
+	}
+	return result, diagnostics, nil
+}
+
+func compile(content []byte, d *cc0_error.Diagnostics, optimizes bool) *Result {
+	p := parser.Parse(bufio.NewScanner(bytes.NewReader(content)), d)
+	if d.ShouldStop() {
+		return nil
+	}
+	globalSymbolTable := analyzer.Run(p, d)
+	if d.HasErrors() {
+		return nil
+	}
+	if optimizes {
+		optimizer.Run(globalSymbolTable)
+	}
+	lines := assembler.Run(globalSymbolTable, d)
+	if d.HasErrors() {
+		return nil
+	}
+
+	var binary bytes.Buffer
+	w := bufio.NewWriter(&binary)
+	if err := compiler.RunOnGeneratedAssembly(lines, w); err != nil {
+		d.Report(cc0_error.Assembler, err)
+	}
+	if d.HasErrors() {
+		return nil
+	}
+	_ = w.Flush()
+	return &Result{Assembly: strings.Join(*lines, ""), Binary: binary.Bytes()}
+}
@@ -0,0 +1,129 @@
+package c0
+
+import (
+	"bytes"
+	"c0_compiler/internal/object"
+	"c0_compiler/internal/vm"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// The operands, many of which are negative. The right ones are also the counts of the shifts.
+var operands = [][2]int32{
+	{17, 5}, {-17, 5}, {17, -5}, {-17, -5},
+	{0, -1}, {-1, 31}, {-1, -1}, {1, 31},
+	{math.MinInt32, -1}, {math.MinInt32, 3}, {math.MaxInt32, -2},
+	{-8, 33}, {12345, -29}, {-12345, 7},
+}
+
+// The operators along with the expected values, which are the ones of C on two's complement ints. The shifts count by
+// the lowest 5 bits of the right operand.
+var operators = []struct {
+	format string
+	apply  func(a, b int32) int32
+}{
+	{"%s %% %s", func(a, b int32) int32 { return a % b }},
+	{"%s & %s", func(a, b int32) int32 { return a & b }},
+	{"%s | %s", func(a, b int32) int32 { return a | b }},
+	{"%s ^ %s", func(a, b int32) int32 { return a ^ b }},
+	{"%s << %s", func(a, b int32) int32 { return a << uint32(b&31) }},
+	{"%s >> %s", func(a, b int32) int32 { return a >> uint32(b&31) }},
+	{"~%s + 0 * %s", func(a, b int32) int32 { return ^a }},
+}
+
+func literalOf(value int32) string {
+	if value == math.MinInt32 {
+		return "(-2147483647 - 1)"
+	}
+	return fmt.Sprintf("(%d)", value)
+}
+
+// Compiles the source and runs it with the input, and returns what it prints.
+func run(t *testing.T, source, input string, optimizes bool) (assembly, output string) {
+	result, diagnostics, err := Compile(strings.NewReader(source), Options{Optimize: optimizes})
+	if err != nil {
+		t.Fatalf("failed to compile: %v %v", err, diagnostics)
+	}
+	file, err := object.Read(bytes.NewReader(result.Binary))
+	if err != nil {
+		t.Fatalf("failed to read the binary: %v", err)
+	}
+	var out bytes.Buffer
+	if err := vm.Run(file, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+	return result.Assembly, out.String()
+}
+
+func expectedOutput() string {
+	var expected strings.Builder
+	for _, pair := range operands {
+		for _, operator := range operators {
+			fmt.Fprintf(&expected, "%d\n", operator.apply(pair[0], pair[1]))
+		}
+	}
+	return expected.String()
+}
+
+func TestOperatorsOnNegativeOperands(t *testing.T) {
+	var source, input strings.Builder
+	source.WriteString("int main() {\n\tint a, b;\n")
+	for _, pair := range operands {
+		fmt.Fprintf(&input, "%d %d\n", pair[0], pair[1])
+		source.WriteString("\tscan(a);\n\tscan(b);\n")
+		for _, operator := range operators {
+			fmt.Fprintf(&source, "\tprint(%s);\n", fmt.Sprintf(operator.format, "a", "b"))
+		}
+	}
+	source.WriteString("\treturn 0;\n}\n")
+
+	for _, optimizes := range []bool{false, true} {
+		if _, output := run(t, source.String(), input.String(), optimizes); output != expectedOutput() {
+			t.Errorf("optimized = %v, output =\n%s\nwant\n%s", optimizes, output, expectedOutput())
+		}
+	}
+}
+
+func TestFoldedOperatorsOnNegativeOperands(t *testing.T) {
+	var source strings.Builder
+	source.WriteString("int main() {\n")
+	for _, pair := range operands {
+		for _, operator := range operators {
+			fmt.Fprintf(&source, "\tprint(%s);\n", fmt.Sprintf(operator.format, literalOf(pair[0]), literalOf(pair[1])))
+		}
+	}
+	source.WriteString("\treturn 0;\n}\n")
+
+	assembly, output := run(t, source.String(), "", false)
+	if output != expectedOutput() {
+		t.Errorf("output =\n%s\nwant\n%s", output, expectedOutput())
+	}
+	if strings.Contains(assembly, "call") {
+		t.Errorf("the operators on the literals are not folded:\n%s", assembly)
+	}
+}
+
+func TestPrecedenceOfOperators(t *testing.T) {
+	for source, want := range map[string]int32{
+		"1 + 2 * 3 & 5 | 8 ^ 3 << 1": 15,
+		"7 % 3 * 2":                  2,
+		"-7 % 3 + 1 << 2":            0,
+		"1 << 2 + 1":                 8,
+		"6 & 3 ^ 5 | 16":             23,
+		"~5 & 7 | -16 >> 2":          -2,
+	} {
+		// A scanned 0 is added to each literal so that the expression is not folded
+		scanned := regexp.MustCompile(`\d+`).ReplaceAllString(source, "($0 + zero)")
+		for _, program := range []string{
+			fmt.Sprintf("int main() {\n\tprint(%s);\n\treturn 0;\n}\n", source),
+			fmt.Sprintf("int main() {\n\tint zero;\n\tscan(zero);\n\tprint(%s);\n\treturn 0;\n}\n", scanned),
+		} {
+			if _, output := run(t, program, "0", false); output != fmt.Sprintf("%d\n", want) {
+				t.Errorf("%s = %s, want %d", source, strings.TrimSpace(output), want)
+			}
+		}
+	}
+}